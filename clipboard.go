@@ -0,0 +1,23 @@
+package shell
+
+import (
+	"github.com/DomBlack/bubble-shell/internal/clipboard"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// copyToClipboard returns a [tea.Cmd] which copies the text to the clipboard of the terminal
+// the shell is running in, adding an internal error to the history if it fails
+func (m Model) copyToClipboard(text string) tea.Cmd {
+	return func() tea.Msg {
+		err := clipboard.Copy(m.cfg.Output, text)
+		if err == nil {
+			return nil
+		}
+
+		item := history.NewItem("", "error copying to the clipboard", history.ErrorStatus)
+		item.ItemType = history.InternalError
+		item.Error = err
+		return m.history.AppendItem(item)()
+	}
+}
@@ -78,6 +78,7 @@ func main() {
 			),
 		),
 		tea.WithAltScreen(),
+		tea.WithMouseCellMotion(), // Lets the mouse wheel scroll the history, but stops the terminal selecting text
 	)
 	_, err := p.Run()
 	if err != nil {
@@ -0,0 +1,357 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DomBlack/bubble-shell/internal/chanwriter"
+	"github.com/DomBlack/bubble-shell/internal/cobrautils"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/stdin"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/DomBlack/bubble-shell/internal/termemu"
+	"github.com/DomBlack/bubble-shell/pkg/interact"
+	"github.com/DomBlack/bubble-shell/pkg/progress"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// ExecuteCommand parses and executes the line of the given history item
+//
+// Any references to previous commands in the line, such as `!!` or `^old^new`, are expanded
+// first (see [syntax.ExpandHistory]), with the item updated to show the expanded line.
+//
+// If the line contains multiple pipelines (i.e. `build && deploy`), then each
+// pipeline is given its own [history.SubCommand] item after the given item,
+// and the given item is used to track the overall status of the line.
+//
+// Lists ending with `&` are started as background jobs, and the shell does not
+// wait for them to finish before running the rest of the line.
+func (m Model) ExecuteCommand(cmd history.Item) tea.Cmd {
+	commands := history.Commands(m.history.Items)
+	lines := make([]string, len(commands))
+	for i, command := range commands {
+		lines[i] = command.Line
+	}
+
+	line, expanded, err := syntax.ExpandHistory(cmd.Line, lines)
+	if err != nil {
+		cmd.Finished = time.Now()
+		cmd.Status = history.ErrorStatus
+		cmd.Error = errors.Wrap(err, "unable to expand history")
+
+		return tea.Sequence(
+			m.history.UpdateItem(cmd),
+			m.Enter(&CommandEntryMode{}),
+		)
+	}
+
+	if expanded {
+		cmd.Line = line
+		return tea.Sequence(
+			m.history.UpdateItem(cmd),
+			m.executeLine(cmd),
+		)
+	}
+	return m.executeLine(cmd)
+}
+
+// executeLine parses and executes the line of the given history item,
+// once any history references within it have been expanded
+func (m Model) executeLine(cmd history.Item) tea.Cmd {
+	list, err := syntax.Parse(cmd.Line)
+	if err != nil {
+		cmd.Finished = time.Now()
+		cmd.Status = history.ErrorStatus
+		cmd.Error = errors.Wrap(err, "unable to parse command")
+
+		return tea.Sequence(
+			m.history.UpdateItem(cmd),
+			m.Enter(&CommandEntryMode{}),
+		)
+	}
+
+	// If the whole line is a single background job, then the item for the line is used for the job
+	if len(list.Items) == 1 && list.Items[0].Background {
+		cmd, startJob := m.startJob(cmd, list.Items[0])
+
+		return tea.Sequence(
+			m.history.UpdateItem(cmd),
+			startJob,
+			m.Enter(&CommandEntryMode{}),
+		)
+	}
+
+	ctx, cancel := context.WithCancel(session.NewContext(m.cfg.RootContext, m.session))
+	input := stdin.New(ctx)
+	ctx = stdin.NewContext(ctx, input)
+	ctx = interact.NewContext(ctx, m.asker)
+	ctx = cobrautils.WithShell(ctx, m.shellAccess)
+	setCancel := func() tea.Msg {
+		return currentCmdContextCancelFuncMsg{m.id, cancel, input}
+	}
+
+	if len(list.Items) == 1 && len(list.Items[0].Pipelines) == 1 {
+		// Track if the last command run asked for its output to be paged
+		paged := false
+		ctx = cobrautils.WithExecutedHook(ctx, func(cmd *cobra.Command) {
+			_, paged = cmd.Annotations[PagerAnnotation]
+		})
+
+		return tea.Batch(
+			setCancel,
+			m.executePipeline(ctx, cmd, list.Items[0].Pipelines[0], func(cmd history.Item, _ error) tea.Cmd {
+				cancel()
+
+				cmds := []tea.Cmd{
+					m.history.UpdateItem(cmd),    // Create an UpdateItem [tea.Cmd]
+					m.Enter(&CommandEntryMode{}), // Then switch back to command entry mode
+				}
+				if paged && lipgloss.Height(cmd.Output) > m.height {
+					cmds = append(cmds, m.Enter(&PagerMode{ItemID: cmd.ID}))
+				}
+
+				return tea.Sequence(cmds...)
+			}),
+		)
+	}
+
+	return tea.Batch(
+		setCancel,
+		m.executeSequence(ctx, cancel, cmd, list.Items, nil),
+	)
+}
+
+// executeSequence executes the next pipeline which should be run given the result of the
+// previously run pipeline, adding a [history.SubCommand] item for it to the history.
+// Background lists are started as jobs with their own item, and then the sequence continues
+// without waiting for them.
+//
+// Once there are no more pipelines to run, the parent item is marked as finished with the
+// status of the last pipeline run.
+func (m Model) executeSequence(ctx context.Context, cancel context.CancelFunc, parent history.Item, items []syntax.AndOr, lastErr error) tea.Cmd {
+	for len(items) > 0 && ctx.Err() == nil {
+		item := items[0]
+
+		if item.Background {
+			child := history.NewItem("", item.Source+" &", history.RunningStatus)
+			child.ItemType = history.SubCommand
+			child, startJob := m.startJob(child, item)
+
+			return tea.Sequence(
+				m.history.AppendItem(child),
+				startJob,
+				m.executeSequence(ctx, cancel, parent, items[1:], nil),
+			)
+		}
+
+		if len(item.Pipelines) == 0 {
+			items = items[1:]
+			continue
+		}
+
+		// Consume the first pipeline of the list, leaving the rest to be run afterwards
+		pipeline := item.Pipelines[0]
+		item.Pipelines = item.Pipelines[1:]
+		items = append([]syntax.AndOr{item}, items[1:]...)
+
+		if !pipeline.Condition.ShouldRun(lastErr == nil) {
+			continue
+		}
+
+		child := history.NewItem("", pipeline.Source, history.RunningStatus)
+		child.ItemType = history.SubCommand
+
+		return tea.Sequence(
+			m.history.AppendItem(child),
+			m.executePipeline(ctx, child, pipeline, func(child history.Item, err error) tea.Cmd {
+				return tea.Sequence(
+					m.history.UpdateItem(child),
+					m.executeSequence(ctx, cancel, parent, items, err),
+				)
+			}),
+		)
+	}
+
+	parent.Finished = time.Now()
+	parent.Status = history.SuccessStatus
+	if lastErr != nil || ctx.Err() != nil {
+		parent.Status = history.ErrorStatus
+	}
+	cancel()
+
+	return tea.Sequence(
+		m.history.UpdateItem(parent),
+		m.Enter(&CommandEntryMode{}),
+	)
+}
+
+// startJob creates a background job for the list, returning the history item updated with
+// the job number and a [tea.Cmd] which will start the job running.
+//
+// The returned item must be added to the history before the job is started, otherwise
+// output from the job may be lost.
+func (m Model) startJob(cmd history.Item, list syntax.AndOr) (history.Item, tea.Cmd) {
+	// Jobs have their own context, so they are not cancelled along with the foreground command
+	ctx, cancel := context.WithCancel(session.NewContext(m.cfg.RootContext, m.session))
+	job := m.session.StartJob(list.Source, cancel)
+	cmd.Job = job.ID
+
+	return cmd, func() tea.Msg {
+		return runJobMsg{
+			id:   m.id,
+			ctx:  ctx,
+			job:  job,
+			item: cmd,
+			list: list,
+		}
+	}
+}
+
+// executeJob runs the pipelines of a background job, streaming the output of all of
+// them into the given history item.
+func (m Model) executeJob(ctx context.Context, job *session.Job, cmd history.Item, list syntax.AndOr) tea.Cmd {
+	w := chanwriter.New()
+	ctx, cmd = m.trackProgress(ctx, cmd)
+
+	return tea.Sequence(m.history.UpdateItem(cmd), tea.Batch(
+		chanwriter.Read(w, m.history.StreamOutputFor(cmd)),
+		func() tea.Msg {
+			defer func() { _ = w.Close() }()
+
+			stdoutBuffer := new(bytes.Buffer)
+			dualW := io.MultiWriter(stdoutBuffer, w)
+
+			var err error
+			for _, pipeline := range list.Pipelines {
+				if ctx.Err() != nil {
+					err = ctx.Err()
+					break
+				}
+				if !pipeline.Condition.ShouldRun(err == nil) {
+					continue
+				}
+
+				// Background jobs can not read from the terminal, so are given an empty input
+				var redirects []cobrautils.RedirectResult
+				redirects, err = m.executor.ExecutePipeline(ctx, pipeline, strings.NewReader(""), dualW, dualW)
+				cmd.Redirects = append(cmd.Redirects, redirectionsForHistory(redirects)...)
+			}
+			m.session.FinishJob(job, err)
+
+			cmd.Finished = time.Now()
+			cmd.Status = history.SuccessStatus
+			switch state, _ := m.session.JobState(job); state {
+			case session.JobKilled:
+				cmd.Status = history.ErrorStatus
+				cmd.Error = errors.Newf("job %d was killed", job.ID)
+			case session.JobFailed:
+				cmd.Status = history.ErrorStatus
+				cmd.Error = err
+			}
+
+			// Capture the stdout as it would be shown by a terminal
+			cmd.Output = strings.TrimSpace(termemu.Render(stdoutBuffer.Bytes()))
+
+			return m.history.UpdateItem(cmd)()
+		},
+	))
+}
+
+// executePipeline executes the pipeline, streaming the output into the given history item.
+// The pipeline reads from the [stdin.Input] carried by the context, which is echoed into the output.
+//
+// Once the pipeline has finished onFinish is called with the updated history item
+// and the error returned by the pipeline.
+func (m Model) executePipeline(ctx context.Context, cmd history.Item, pipeline syntax.Pipeline, onFinish func(cmd history.Item, err error) tea.Cmd) tea.Cmd {
+	w := chanwriter.New()
+	ctx, cmd = m.trackProgress(ctx, cmd)
+
+	return tea.Sequence(m.history.UpdateItem(cmd), tea.Batch(
+		chanwriter.Read(w, m.history.StreamOutputFor(cmd)),
+		func() tea.Msg {
+			defer func() { _ = w.Close() }()
+
+			stdoutBuffer := new(bytes.Buffer)
+			dualW := &syncWriter{w: io.MultiWriter(stdoutBuffer, w)}
+
+			var in io.Reader = strings.NewReader("")
+			if input := stdin.FromContext(ctx); input != nil {
+				input.SetEcho(dualW)
+				defer input.SetEcho(nil)
+				in = input
+			}
+
+			redirects, err := m.executor.ExecutePipeline(ctx, pipeline, in, dualW, dualW)
+			m.session.SetLastResult(err)
+			cmd.Redirects = redirectionsForHistory(redirects)
+			cmd.Finished = time.Now()
+			if err != nil {
+				cmd.Status = history.ErrorStatus
+				cmd.Error = err
+			} else {
+				cmd.Status = history.SuccessStatus
+			}
+
+			// Capture the stdout as it would be shown by a terminal
+			cmd.Output = strings.TrimSpace(termemu.Render(stdoutBuffer.Bytes()))
+
+			return onFinish(cmd, err)()
+		},
+	))
+}
+
+// trackProgress adds a [progress.Group] to the history item and the context, so any progress
+// reported by the command is rendered within the item. The item must be updated in the history
+// before the command is run.
+func (m Model) trackProgress(ctx context.Context, cmd history.Item) (context.Context, history.Item) {
+	cmd.Progress = progress.NewGroup()
+	return progress.NewContext(ctx, cmd.Progress), cmd
+}
+
+// redirectionsForHistory converts the results of the redirects into the form stored in the history
+func redirectionsForHistory(results []cobrautils.RedirectResult) []history.Redirection {
+	if len(results) == 0 {
+		return nil
+	}
+
+	redirections := make([]history.Redirection, len(results))
+	for i, result := range results {
+		stream := "stdout"
+		switch result.Fd {
+		case syntax.Stdin:
+			stream = "stdin"
+		case syntax.Stderr:
+			stream = "stderr"
+		}
+
+		redirections[i] = history.Redirection{
+			Stream: stream,
+			Path:   result.Path,
+			Append: result.Kind == syntax.RedirectAppend,
+			Bytes:  result.Bytes,
+		}
+	}
+	return redirections
+}
+
+// syncWriter is a writer which can be written to from multiple goroutines,
+// such as by a command and the lines the user types being echoed
+type syncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *syncWriter) Write(p []byte) (n int, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.w.Write(p)
+}
@@ -0,0 +1,49 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+func TestForegroundCommandWhileJobRuns(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	echoed := make(chan struct{})
+	var echoOnce bool
+
+	rootCmd := testRootCmd()
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "block",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			close(started)
+			<-release
+			return nil
+		},
+	})
+
+	// The job and the foreground command share the same command tree
+	runShell(t, New(rootCmd, WithNoHistory()),
+		func(m Model) {
+			for _, item := range m.history.Items {
+				if item.Line == "echo foreground" && item.Status == history.SuccessStatus && !echoOnce {
+					echoOnce = true
+					close(echoed)
+				}
+			}
+		},
+		func(p *tea.Program) {
+			defer close(release)
+
+			enterLine(p, "block &")
+			if !wait(t, started, "the job to start") {
+				return
+			}
+
+			enterLine(p, "echo foreground")
+			wait(t, echoed, "the foreground command to finish while the job is running")
+		},
+	)
+}
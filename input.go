@@ -0,0 +1,25 @@
+package shell
+
+import (
+	"context"
+
+	"github.com/DomBlack/bubble-shell/internal/stdin"
+)
+
+// SetInputMasked sets whether the input the user types for a running command is
+// masked, so sensitive input such as passwords is not shown on screen or echoed
+// into the output of the command. ctx must be the context of the command, from
+// cmd.Context().
+//
+// It has no effect if the command is not being run by the interactive shell.
+//
+//	shell.SetInputMasked(cmd.Context(), true)
+//	defer shell.SetInputMasked(cmd.Context(), false)
+//
+//	fmt.Fprint(cmd.OutOrStdout(), "Password: ")
+//	password, err := bufio.NewReader(cmd.InOrStdin()).ReadString('\n')
+func SetInputMasked(ctx context.Context, masked bool) {
+	if input := stdin.FromContext(ctx); input != nil {
+		input.SetMasked(masked)
+	}
+}
@@ -0,0 +1,186 @@
+// Package asciicast reads and writes recordings of terminal sessions in the
+// asciicast v2 format used by asciinema, so they can be played back by the shell,
+// the asciinema player or uploaded to asciinema.org.
+//
+// A recording is a JSON header on the first line, followed by one JSON array per
+// line for each event; [time, type, data], where time is the number of seconds
+// since the start of the recording.
+package asciicast
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Version is the version of the asciicast format which is read and written
+const Version = 2
+
+// Types of events within a recording
+const (
+	OutputEvent = "o" // Data written to the terminal
+	InputEvent  = "i" // Data typed by the user
+	ResizeEvent = "r" // The terminal was resized, with the data being "{width}x{height}"
+	MarkerEvent = "m" // A marker, such as the start of a chapter
+)
+
+// Header is the first line of a recording, describing the terminal it was recorded in
+type Header struct {
+	Version       int               `json:"version"`
+	Width         int               `json:"width"`
+	Height        int               `json:"height"`
+	Timestamp     int64             `json:"timestamp,omitempty"`       // Unix time the recording was started
+	IdleTimeLimit float64           `json:"idle_time_limit,omitempty"` // The longest pause between events during playback, in seconds
+	Title         string            `json:"title,omitempty"`
+	Env           map[string]string `json:"env,omitempty"`
+}
+
+// Event is something which happened in the terminal during the recording
+type Event struct {
+	Time float64 // Seconds since the start of the recording
+	Type string  // The type of event, such as [OutputEvent]
+	Data string
+}
+
+// MarshalJSON encodes the event as the [time, type, data] array used by the format
+func (e Event) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode([]any{e.Time, e.Type, e.Data}); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// UnmarshalJSON decodes the event from the [time, type, data] array used by the format
+func (e *Event) UnmarshalJSON(data []byte) error {
+	var fields []json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	if len(fields) != 3 {
+		return errors.Newf("expected an event to have 3 fields, got %d", len(fields))
+	}
+
+	if err := json.Unmarshal(fields[0], &e.Time); err != nil {
+		return errors.Wrap(err, "invalid event time")
+	}
+	if err := json.Unmarshal(fields[1], &e.Type); err != nil {
+		return errors.Wrap(err, "invalid event type")
+	}
+	if err := json.Unmarshal(fields[2], &e.Data); err != nil {
+		return errors.Wrap(err, "invalid event data")
+	}
+	return nil
+}
+
+// Elapsed returns the time from the start of the recording to the event
+func (e Event) Elapsed() time.Duration {
+	return time.Duration(e.Time * float64(time.Second))
+}
+
+// Recording is a recording of a terminal session read with [Read]
+type Recording struct {
+	Header Header
+	Events []Event
+}
+
+// Duration returns the time from the start of the recording to its last event
+func (r *Recording) Duration() time.Duration {
+	if len(r.Events) == 0 {
+		return 0
+	}
+	return r.Events[len(r.Events)-1].Elapsed()
+}
+
+// Read reads a recording in the asciicast v2 format
+func Read(r io.Reader) (*Recording, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, errors.Wrap(err, "unable to read the recording")
+		}
+		return nil, errors.New("the recording is empty")
+	}
+
+	recording := &Recording{}
+	if err := json.Unmarshal(scanner.Bytes(), &recording.Header); err != nil {
+		return nil, errors.Wrap(err, "unable to read the header of the recording")
+	}
+	if recording.Header.Version != Version {
+		return nil, errors.Newf("unsupported asciicast version %d, only version %d is supported", recording.Header.Version, Version)
+	}
+
+	for line := 2; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, errors.Wrapf(err, "unable to read the event on line %d", line)
+		}
+		recording.Events = append(recording.Events, event)
+	}
+
+	return recording, errors.Wrap(scanner.Err(), "unable to read the recording")
+}
+
+// Recorder writes the events of a terminal session to a recording as they happen
+//
+// It is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex
+	w       io.Writer
+	enc     *json.Encoder
+	started time.Time
+	now     func() time.Time
+}
+
+// NewRecorder writes the header to w and returns a recorder which writes events
+// to w, timed from when the recorder was created.
+func NewRecorder(w io.Writer, header Header) (*Recorder, error) {
+	r := &Recorder{w: w, enc: json.NewEncoder(w), now: time.Now}
+	r.enc.SetEscapeHTML(false)
+	r.started = r.now()
+
+	header.Version = Version
+	if header.Timestamp == 0 {
+		header.Timestamp = r.started.Unix()
+	}
+	if err := r.enc.Encode(header); err != nil {
+		return nil, errors.Wrap(err, "unable to write the header of the recording")
+	}
+
+	return r, nil
+}
+
+// Output records the data being written to the terminal
+func (r *Recorder) Output(data string) error {
+	return r.record(OutputEvent, data)
+}
+
+// Resize records the terminal being resized
+func (r *Recorder) Resize(width, height int) error {
+	return r.record(ResizeEvent, strconv.Itoa(width)+"x"+strconv.Itoa(height))
+}
+
+// record writes an event which happened now to the recording
+func (r *Recorder) record(eventType string, data string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Times are rounded to microseconds, as asciinema does
+	elapsed := r.now().Sub(r.started).Round(time.Microsecond).Seconds()
+
+	return errors.Wrap(r.enc.Encode(Event{Time: elapsed, Type: eventType, Data: data}), "unable to write to the recording")
+}
@@ -0,0 +1,71 @@
+package asciicast
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	var buf bytes.Buffer
+	recorder, err := NewRecorder(&buf, Header{Width: 80, Height: 24, Title: "demo"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	start := recorder.started
+	recorder.now = func() time.Time { return start.Add(1500 * time.Millisecond) }
+	if err := recorder.Output("hello \"world\" <b>\r\n\x1b[31m"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	recorder.now = func() time.Time { return start.Add(2 * time.Second) }
+	if err := recorder.Resize(100, 30); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected a header and 2 events but got:\n%s", buf.String())
+	}
+	if expected := `[1.5,"o","hello \"world\" <b>\r\n\u001b[31m"]`; lines[1] != expected {
+		t.Errorf("expected the output event to be %s but got %s", expected, lines[1])
+	}
+
+	recording, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("unexpected error reading the recording: %v", err)
+	}
+
+	if recording.Header.Version != Version || recording.Header.Width != 80 || recording.Header.Height != 24 || recording.Header.Title != "demo" {
+		t.Errorf("expected the header to be read back but got %+v", recording.Header)
+	}
+
+	expected := []Event{
+		{Time: 1.5, Type: OutputEvent, Data: "hello \"world\" <b>\r\n\x1b[31m"},
+		{Time: 2, Type: ResizeEvent, Data: "100x30"},
+	}
+	if !reflect.DeepEqual(recording.Events, expected) {
+		t.Errorf("expected events %+v but got %+v", expected, recording.Events)
+	}
+	if recording.Duration() != 2*time.Second {
+		t.Errorf("expected a duration of 2s but got %s", recording.Duration())
+	}
+}
+
+func TestReadInvalid(t *testing.T) {
+	tests := []string{
+		"",
+		"not json",
+		`{"version": 1, "width": 80, "height": 24}`,
+		"{\"version\": 2, \"width\": 80, \"height\": 24}\n[1.0, \"o\"]",
+		"{\"version\": 2, \"width\": 80, \"height\": 24}\n{\"time\": 1}",
+	}
+
+	for _, test := range tests {
+		if _, err := Read(strings.NewReader(test)); err == nil {
+			t.Errorf("Read(%q): expected an error", test)
+		}
+	}
+}
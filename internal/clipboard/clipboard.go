@@ -0,0 +1,39 @@
+// Package clipboard copies text to the system clipboard of the terminal the shell
+// is running in, using the OSC 52 escape sequence.
+//
+// As the sequence is interpreted by the terminal rather than the machine the shell
+// is running on, copying works even when the shell is being used over SSH.
+package clipboard
+
+import (
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Sequence returns the escape sequence which tells the terminal to copy the text to the clipboard
+//
+// If the shell is running inside tmux, the sequence is wrapped so tmux passes it
+// through to the terminal it is running in.
+func Sequence(text string) string {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+
+	if os.Getenv("TMUX") != "" {
+		seq = "\x1bPtmux;" + strings.ReplaceAll(seq, "\x1b", "\x1b\x1b") + "\x1b\\"
+	}
+
+	return seq
+}
+
+// Copy writes the escape sequence to copy the text to the clipboard to w,
+// which should be the terminal the shell is being rendered to.
+//
+// The sequence is written with a single write, so it can't be split up by
+// anything else being rendered to w at the same time.
+func Copy(w io.Writer, text string) error {
+	_, err := io.WriteString(w, Sequence(text))
+	return errors.Wrap(err, "unable to copy to the clipboard")
+}
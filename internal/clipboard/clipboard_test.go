@@ -0,0 +1,29 @@
+package clipboard
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCopy(t *testing.T) {
+	t.Setenv("TMUX", "")
+
+	var buf bytes.Buffer
+	if err := Copy(&buf, "hello world"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "\x1b]52;c;aGVsbG8gd29ybGQ=\a"
+	if buf.String() != expected {
+		t.Errorf("expected %q but got %q", expected, buf.String())
+	}
+}
+
+func TestSequenceWithinTmux(t *testing.T) {
+	t.Setenv("TMUX", "/tmp/tmux-1000/default,1,0")
+
+	expected := "\x1bPtmux;\x1b\x1b]52;c;aGk=\a\x1b\\"
+	if got := Sequence("hi"); got != expected {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
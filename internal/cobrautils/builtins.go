@@ -0,0 +1,411 @@
+package cobrautils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// sessionCommands are the builtin commands which act on the shell session.
+//
+// As they do not use the command tree themselves, they are run on their own
+// rather than through the root command. This allows them to be used while another
+// command is running, such as `kill` stopping a background job.
+var sessionCommands map[string]func() *cobra.Command
+
+func init() {
+	// This is set in init as the source command executes commands, which
+	// would otherwise create an initialization cycle
+	sessionCommands = map[string]func() *cobra.Command{
+		"set":     setCmd,
+		"unset":   unsetCmd,
+		"vars":    varsCmd,
+		"alias":   aliasCmd,
+		"unalias": unaliasCmd,
+		"jobs":    jobsCmd,
+		"fg":      fgCmd,
+		"kill":    killCmd,
+		"source":  sourceCmd,
+		"copy":    copyCmd,
+		"export":  exportCmd,
+		"replay":  replayCmd,
+		"history": historyCmd,
+	}
+}
+
+// addDefaultShellCommands adds the default shell commands to the root command, returning
+// the session commands which were added keyed by their name.
+//
+// Any default command with the same name or alias as one of the root command's own commands
+// is not added, so the application's command is the one which is run.
+func addDefaultShellCommands(rootCmd *cobra.Command) map[string]func() *cobra.Command {
+	if exit := exitCmd(); !hasCommand(rootCmd, exit) {
+		rootCmd.AddCommand(exit)
+	}
+
+	// The session commands are also added to the root command so
+	// they are included in the help and autocomplete
+	added := make(map[string]func() *cobra.Command, len(sessionCommands))
+	for name, newCmd := range sessionCommands {
+		if cmd := newCmd(); !hasCommand(rootCmd, cmd) {
+			rootCmd.AddCommand(cmd)
+			added[name] = newCmd
+		}
+	}
+	return added
+}
+
+// hasCommand reports whether the root command has a command with the same name or alias as cmd
+func hasCommand(rootCmd *cobra.Command, cmd *cobra.Command) bool {
+	names := append([]string{cmd.Name()}, cmd.Aliases...)
+	for _, existing := range rootCmd.Commands() {
+		for _, name := range names {
+			if existing.Name() == name || existing.HasAlias(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// executeSessionCommand executes one of the [sessionCommands] on its own
+func executeSessionCommand(ctx context.Context, cmd *cobra.Command, args []string, in io.Reader, stdout io.Writer, stderr io.Writer) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+
+	cmd.SetIn(in)
+	cmd.SetOut(stdout)
+	cmd.SetErr(stderr)
+	cmd.SetContext(ctx)
+	cmd.SetArgs(args)
+
+	return errors.WithStack(cmd.Execute())
+}
+
+func exitCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "exit",
+		Short:   "Exit the shell",
+		Aliases: []string{"quit"},
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// no-op which is needed otherwise Cobra won't list the help for it
+			// when the user runs `help exit`
+			//
+			// However the shell will intercept this command and exit the shell
+			// before running this function
+			return nil
+		},
+	}
+}
+
+func setCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "set name value",
+		Short: "Set a shell variable",
+		Long: "Set a shell variable which can be used in later commands as $name or ${name}.\n\n" +
+			"Variables set in the shell take priority over environment variables of the same name.",
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			return s.Set(args[0], args[1])
+		},
+	}
+}
+
+func unsetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unset name...",
+		Short: "Remove shell variables",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range args {
+				if !s.Unset(name) {
+					return errors.Newf("variable %q is not set", name)
+				}
+			}
+			return nil
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			s := session.FromContext(cmd.Context())
+			if s == nil {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return s.VarNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+	}
+}
+
+func varsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "vars",
+		Short: "List the shell variables",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range s.VarNames() {
+				value, _ := s.Lookup(name)
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "%s=%s\n", name, syntax.Quote(value))
+			}
+			return nil
+		},
+	}
+}
+
+func aliasCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "alias [name[=value]...]",
+		Short: "Define or list aliases",
+		Long: "Define an alias which is replaced by its value when used as a command name, " +
+			"such as `alias dep='deploy --env staging'`.\n\n" +
+			"Given just a name the alias is printed, and with no arguments all aliases are listed.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			if len(args) == 0 {
+				args = s.AliasNames()
+			}
+
+			for _, arg := range args {
+				name, value, isDefinition := strings.Cut(arg, "=")
+				if isDefinition {
+					if err := s.SetAlias(name, value); err != nil {
+						return err
+					}
+					continue
+				}
+
+				value, found := s.Alias(name)
+				if !found {
+					return errors.Newf("alias %q is not defined", name)
+				}
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "alias %s=%s\n", name, syntax.Quote(value))
+			}
+			return nil
+		},
+		ValidArgsFunction: completeAliasNames,
+	}
+}
+
+func unaliasCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "unalias name...",
+		Short: "Remove aliases",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, name := range args {
+				found, err := s.RemoveAlias(name)
+				if err != nil {
+					return err
+				}
+				if !found {
+					return errors.Newf("alias %q is not defined", name)
+				}
+			}
+			return nil
+		},
+		ValidArgsFunction: completeAliasNames,
+	}
+}
+
+// completeAliasNames is a [cobra.Command.ValidArgsFunction] which completes the names of aliases
+func completeAliasNames(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	s := session.FromContext(cmd.Context())
+	if s == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return s.AliasNames(), cobra.ShellCompDirectiveNoFileComp
+}
+
+func jobsCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "jobs",
+		Short: "List the background jobs",
+		Long: "List the jobs started by ending a command with `&`.\n\n" +
+			"Jobs which have finished are removed once they have been listed.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, job := range s.Jobs() {
+				state, _ := s.JobState(job)
+				_, _ = fmt.Fprintf(cmd.OutOrStdout(), "[%d] %-8s %s\n", job.ID, state, job.Line)
+
+				if state != session.JobRunning {
+					s.RemoveJob(job)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+func fgCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "fg [job]",
+		Short: "Wait for a background job to finish",
+		Long: "Wait for a background job to finish, such as `fg %1`, returning the error from the job if it fails. " +
+			"If no job is given the most recently started job is used.\n\n" +
+			"Cancelling the command while it is waiting will also stop the job.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			spec := ""
+			if len(args) > 0 {
+				spec = args[0]
+			}
+			job, err := s.FindJob(spec)
+			if err != nil {
+				return err
+			}
+
+			_, _ = fmt.Fprintln(cmd.OutOrStdout(), job.Line)
+
+			select {
+			case <-job.Done():
+			case <-cmd.Context().Done():
+				_ = s.KillJob(job)
+				<-job.Done()
+			}
+			s.RemoveJob(job)
+
+			switch state, err := s.JobState(job); state {
+			case session.JobKilled:
+				return errors.Newf("job %d was killed", job.ID)
+			case session.JobFailed:
+				return errors.Wrapf(err, "job %d failed", job.ID)
+			default:
+				return nil
+			}
+		},
+		ValidArgsFunction: completeJobSpecs,
+	}
+}
+
+func killCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "kill job...",
+		Short: "Stop background jobs",
+		Long:  "Stop background jobs by cancelling the context of the commands they are running, such as `kill %1`.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			s, err := sessionFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			for _, spec := range args {
+				job, err := s.FindJob(spec)
+				if err != nil {
+					return err
+				}
+
+				if err := s.KillJob(job); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		ValidArgsFunction: completeJobSpecs,
+	}
+}
+
+func sourceCmd() *cobra.Command {
+	var continueOnError bool
+
+	cmd := &cobra.Command{
+		Use:   "source file",
+		Short: "Run the commands in a file",
+		Long: "Run each line of the file as if it had been typed into the shell, sharing the variables " +
+			"and aliases of this shell. Running stops at the first line which fails, unless --continue-on-error is set.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			executor, ok := cmd.Context().Value(executorKey{}).(*Executor)
+			if !ok {
+				return errors.Newf("%s can only be run within a shell session", cmd.Name())
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return errors.Wrapf(err, "unable to open %s", args[0])
+			}
+			defer func() { _ = file.Close() }()
+
+			opts := ScriptOptions{ContinueOnError: continueOnError}
+			if continueOnError {
+				// When stopping the error is returned instead, so it's only reported once
+				opts.ReportError = func(err error) {
+					_, _ = fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+				}
+			}
+
+			return executor.RunScript(cmd.Context(), file, cmd.OutOrStdout(), cmd.ErrOrStderr(), opts)
+		},
+	}
+	cmd.Flags().BoolVarP(&continueOnError, "continue-on-error", "k", false, "keep running the following lines after a line fails")
+
+	return cmd
+}
+
+// completeJobSpecs is a [cobra.Command.ValidArgsFunction] which completes the specs of running jobs
+func completeJobSpecs(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	s := session.FromContext(cmd.Context())
+	if s == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	var specs []string
+	for _, job := range s.Jobs() {
+		if state, _ := s.JobState(job); state == session.JobRunning {
+			specs = append(specs, fmt.Sprintf("%%%d\t%s", job.ID, job.Line))
+		}
+	}
+	return specs, cobra.ShellCompDirectiveNoFileComp
+}
+
+// executorKey is the context key for the [Executor] running a session command
+type executorKey struct{}
+
+// sessionFor returns the shell session the command is being run within
+func sessionFor(cmd *cobra.Command) (*session.Session, error) {
+	s := session.FromContext(cmd.Context())
+	if s == nil {
+		return nil, errors.Newf("%s can only be run within a shell session", cmd.Name())
+	}
+	return s, nil
+}
@@ -1,28 +1,37 @@
 package cobrautils
 
 import (
+	"bytes"
 	"context"
 	"io"
 	"os"
-	"strings"
 	"sync"
 
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
 	"github.com/cockroachdb/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 )
 
 var (
-	stdoutCaptureMu sync.Mutex
+	// stdoutCaptureLock is held while os.Stdout and os.Stderr are replaced to capture
+	// the output of a command, as they are shared by the whole process
+	stdoutCaptureLock = make(chan struct{}, 1)
+
+	// stdoutSwapMu is held while os.Stdout and os.Stderr are being swapped, and read locked by
+	// autocomplete requests, which are not captured but still have cobra read them as defaults
+	stdoutSwapMu sync.RWMutex
 )
 
 // InitRootCmd sets up the root command for the shell and calls
 // some of the same functions that would run behind the scenes for
 // `rootCmd.Execute()`.
 //
-// These are needed to do stuff like add the internal autocomplete command
-func InitRootCmd(rootCmd *cobra.Command) {
-	addDefaultShellCommands(rootCmd)
+// These are needed to do stuff like add the internal autocomplete command.
+// The builtin session commands which were added to the tree are returned, keyed by their name.
+func InitRootCmd(rootCmd *cobra.Command) map[string]func() *cobra.Command {
+	builtins := addDefaultShellCommands(rootCmd)
 
 	rootCmd.SilenceErrors = true
 	rootCmd.CompletionOptions.HiddenDefaultCmd = true
@@ -34,42 +43,244 @@ func InitRootCmd(rootCmd *cobra.Command) {
 
 	rootCmd.InitDefaultHelpCmd()
 	rootCmd.InitDefaultCompletionCmd()
+
+	return builtins
 }
 
-// ExecuteCmd executes a command and captures the output
-func ExecuteCmd(ctx context.Context, rootCmd *cobra.Command, line string, in io.Reader, stdout io.Writer, stderr io.Writer) error {
-	// Capture stdout and stderr and then restore them when we leave here
-	stdoutCaptureMu.Lock()
-	originalStdOut := os.Stdout
-	originalStdErr := os.Stderr
-	defer func() {
-		os.Stdout = originalStdOut
-		os.Stderr = originalStdErr
-		stdoutCaptureMu.Unlock()
-	}()
+// Executor executes commands from a cobra command tree
+//
+// As cobra stores the parsed flags and arguments of a command on the command itself, the
+// tree is locked while cobra finds the command and parses its flags. Once the command starts
+// running the lock is released, so other commands can be executed on the tree, apart from
+// the same command or any command sharing a flag with it (such as a persistent flag of a
+// parent command), which wait for it to finish. An executor created with [NewFactoryExecutor]
+// creates a new tree for every command, so never has to wait.
+//
+// Autocomplete requests never wait for running commands, as when the tree is in use they are
+// run on a copy of it instead. The copy does not have any completion functions registered
+// for flags with [cobra.Command.RegisterFlagCompletionFunc], so flag values can only be
+// completed when the tree is not in use by the command being completed.
+type Executor struct {
+	rootCmd       *cobra.Command
+	newRootCmd    func() *cobra.Command // If set, creates a new command tree for each command executed
+	captureStdout bool
+	treeLock      chan struct{}   // A lock on the command tree, which can be waited on with a context
+	running       runningCommands // The commands on the tree which are running without the lock
 
-	// Set up our stdout and stderr pipes
-	stdoutR, stdoutW, _ := os.Pipe()
-	stderrR, stderrW, _ := os.Pipe()
-	os.Stdout = stdoutW
-	os.Stderr = stderrW
+	completionCmd  *cobra.Command // A copy of the tree for autocomplete requests while the tree is in use
+	completionLock chan struct{}
 
-	// Copy the output to the correct places
-	var wg sync.WaitGroup
-	wg.Add(2)
-	go func() {
-		_, _ = io.Copy(stdout, stdoutR)
-		wg.Done()
-	}()
-	go func() {
-		_, _ = io.Copy(stderr, stderrR)
-		wg.Done()
-	}()
+	sessionCommands     map[string]func() *cobra.Command // The builtin session commands in the tree, which are run on their own
+	sessionCommandsOnce sync.Once
+}
 
-	// Reset the internal state of the command
-	args := strings.Split(line, " ")
+// NewExecutor initialises the root command with [InitRootCmd] and returns an executor for it.
+//
+// By default, commands are expected to write to cmd.OutOrStdout() and cmd.ErrOrStderr(). If
+// captureStdout is true then anything commands write directly to os.Stdout or os.Stderr is also
+// captured. As that replaces os.Stdout and os.Stderr for the whole process, only one command
+// from all the executors capturing stdout can run at a time. Autocomplete requests are never
+// captured, so are not held up by a running command.
+//
+// A copy of the tree is made for autocomplete requests, so the tree must not be changed afterwards.
+func NewExecutor(rootCmd *cobra.Command, captureStdout bool) *Executor {
+	sessionCommands := InitRootCmd(rootCmd)
+
+	return &Executor{
+		rootCmd:         rootCmd,
+		captureStdout:   captureStdout,
+		treeLock:        make(chan struct{}, 1),
+		sessionCommands: sessionCommands,
+		completionCmd:   cloneForCompletion(rootCmd),
+		completionLock:  make(chan struct{}, 1),
+	}
+}
+
+// NewFactoryExecutor returns an executor which calls newRootCmd to create a new command tree,
+// initialised with [InitRootCmd], for every command it executes.
+//
+// This means no state from previous executions, such as flag values, can leak into
+// later executions. newRootCmd must return a new tree every time it is called.
+//
+// See [NewExecutor] for the behaviour of captureStdout.
+func NewFactoryExecutor(newRootCmd func() *cobra.Command, captureStdout bool) *Executor {
+	return &Executor{
+		newRootCmd:    newRootCmd,
+		captureStdout: captureStdout,
+	}
+}
+
+// sessionCommand returns the builtin session command with the given name, unless the
+// command tree has its own command with that name which should be run instead
+func (e *Executor) sessionCommand(name string) (newCmd func() *cobra.Command, found bool) {
+	// Every tree from the factory has the same commands, so one is built to find which builtins it replaces
+	if e.newRootCmd != nil {
+		e.sessionCommandsOnce.Do(func() {
+			e.sessionCommands = InitRootCmd(e.newRootCmd())
+		})
+	}
+
+	newCmd, found = e.sessionCommands[name]
+	return newCmd, found
+}
+
+// ExecutePipeline executes each command in the pipeline in turn, with the output of
+// each command being used as the input of the next command.
+//
+// As the commands may share the same command tree, they are not run concurrently,
+// so the output of each command is buffered before being passed to the next command.
+// If any command in the pipeline returns an error the pipeline is stopped and
+// that error returned.
+//
+// Aliases and variables within the commands are expanded using the [session.Session] carried by
+// the context, or variables from the environment if there is no session. Any redirections on the
+// commands are applied, with a summary of each returned once the pipeline has finished.
+func (e *Executor) ExecutePipeline(ctx context.Context, pipeline syntax.Pipeline, in io.Reader, stdout io.Writer, stderr io.Writer) ([]RedirectResult, error) {
+	var allResults []RedirectResult
+
+	expand := os.Getenv
+	s := session.FromContext(ctx)
+	if s != nil {
+		expand = s.Expand
+	}
+
+	for i, cmd := range pipeline.Commands {
+		if s != nil {
+			var err error
+			cmd, err = cmd.ExpandAliases(s.Alias)
+			if err != nil {
+				return allResults, err
+			}
+		}
+
+		cmdOut := stdout
+		output := new(bytes.Buffer)
+		if i < len(pipeline.Commands)-1 {
+			cmdOut = output
+		}
+
+		results, err := withRedirects(cmd.Redirects, expand, in, cmdOut, stderr, func(in io.Reader, stdout io.Writer, stderr io.Writer) error {
+			args := cmd.ExpandArgs(expand)
+			if newCmd, found := e.sessionCommand(args[0]); found {
+				ctx := context.WithValue(ctx, executorKey{}, e)
+				ctx = context.WithValue(ctx, pipedInputKey{}, i > 0 || redirectsInput(cmd.Redirects))
+				return executeSessionCommand(ctx, newCmd(), args[1:], in, stdout, stderr)
+			}
+
+			return e.ExecuteArgs(ctx, args, in, stdout, stderr)
+		})
+		allResults = append(allResults, results...)
+		if err != nil {
+			return allResults, err
+		}
+
+		in = output
+	}
+
+	return allResults, nil
+}
+
+type executedHookKey struct{}
+
+// WithExecutedHook returns a context which calls hook with each command
+// executed by [Executor.ExecuteArgs] using the context, once it has finished.
+func WithExecutedHook(ctx context.Context, hook func(cmd *cobra.Command)) context.Context {
+	return context.WithValue(ctx, executedHookKey{}, hook)
+}
+
+// ExecuteArgs executes a command with the given arguments, writing its output to stdout and stderr
+//
+// If the command, or a command sharing one of its flags, is already running on the command
+// tree, this waits for it to finish or returns the context's error if the context is done first.
+func (e *Executor) ExecuteArgs(ctx context.Context, args []string, in io.Reader, stdout io.Writer, stderr io.Writer) error {
+	// Autocomplete requests write the completions to cmd.OutOrStdout(), so there is no need to capture
+	// anything else, which would also make them wait for any running command which is being captured
+	if e.captureStdout && isCompletionRequest(args) {
+		stdoutSwapMu.RLock()
+		defer stdoutSwapMu.RUnlock()
+	} else if e.captureStdout {
+		// This is done before acquiring the tree, as a running command will need the tree again to finish
+		var restore func()
+		var err error
+		stdout, stderr, restore, err = captureProcessOutput(ctx, stdout, stderr)
+		if err != nil {
+			return err
+		}
+		defer restore()
+	}
+
+	rootCmd, cmd, release, err := e.acquireTree(ctx, args)
+	if err != nil {
+		return err
+	}
+	defer release()
 
-	if cmd, _, err := rootCmd.Find(args); err == nil {
+	if cmd != nil {
+		restore := e.releaseTreeWhileRunning(cmd, in, stdout, stderr)
+		defer restore()
+	}
+
+	// Setup the cobra command to output to the write places
+	rootCmd.SetIn(in)
+	rootCmd.SetOut(stdout)
+	rootCmd.SetErr(stderr)
+	rootCmd.SetContext(ctx)
+	rootCmd.SetArgs(args)
+
+	// Finally execute it!
+	executed, err := rootCmd.ExecuteC()
+	if hook, ok := ctx.Value(executedHookKey{}).(func(cmd *cobra.Command)); ok && executed != nil {
+		hook(executed)
+	}
+	return errors.WithStack(err)
+}
+
+// isCompletionRequest reports whether the args are for cobra's hidden command which autocompletes a line
+func isCompletionRequest(args []string) bool {
+	return len(args) > 0 && (args[0] == cobra.ShellCompRequestCmd || args[0] == cobra.ShellCompNoDescRequestCmd)
+}
+
+// acquireTree returns the command tree to execute the args on, and a function
+// to release the tree once the command has finished.
+//
+// For a shared tree, the command the args will execute is also returned if it was found.
+func (e *Executor) acquireTree(ctx context.Context, args []string) (rootCmd *cobra.Command, cmd *cobra.Command, release func(), err error) {
+	// A new tree has no state from previous executions, so doesn't need to be locked or reset
+	if e.newRootCmd != nil {
+		rootCmd = e.newRootCmd()
+		InitRootCmd(rootCmd)
+		return rootCmd, nil, func() {}, nil
+	}
+	rootCmd = e.rootCmd
+
+	if isCompletionRequest(args) {
+		rootCmd, release, err = e.acquireCompletionTree(ctx, args[1:])
+		return rootCmd, nil, release, err
+	}
+
+	for {
+		select {
+		case e.treeLock <- struct{}{}:
+		case <-ctx.Done():
+			return nil, nil, nil, errors.WithStack(ctx.Err())
+		}
+
+		finished := e.running.conflict(rootCmd, findCommand(rootCmd, args))
+		if finished == nil {
+			break
+		}
+
+		// Wait for a running command to finish before checking again
+		<-e.treeLock
+		select {
+		case <-finished:
+		case <-ctx.Done():
+			return nil, nil, nil, errors.WithStack(ctx.Err())
+		}
+	}
+
+	// Reset the internal state of the command
+	if cmd = findCommand(rootCmd, args); cmd != nil {
 		// Reset the context to nil
 		cmd.SetContext(nil)
 
@@ -86,38 +297,104 @@ func ExecuteCmd(ctx context.Context, rootCmd *cobra.Command, line string, in io.
 		cmd.InitDefaultVersionFlag()
 	}
 
-	// Setup the cobra command to output to the write places
-	rootCmd.SetIn(in)
-	rootCmd.SetOut(stdoutW)
-	rootCmd.SetErr(stderrW)
-	rootCmd.SetContext(ctx)
-	rootCmd.SetArgs(args)
+	return rootCmd, cmd, func() { <-e.treeLock }, nil
+}
 
-	// Finally execute it!
-	err := rootCmd.Execute()
+// acquireCompletionTree returns the command tree to complete the args on without waiting for
+// running commands, and a function to release the tree once the completions have been written.
+func (e *Executor) acquireCompletionTree(ctx context.Context, args []string) (rootCmd *cobra.Command, release func(), err error) {
+	// The completions use the flags of the command being completed, so the tree can only be used if
+	// it is free and that command is not running. Otherwise the copy is used, which only autocomplete
+	// requests wait for.
+	select {
+	case e.treeLock <- struct{}{}:
+		if e.running.conflict(e.rootCmd, findCommand(e.rootCmd, args)) == nil {
+			return e.rootCmd, func() { <-e.treeLock }, nil
+		}
+		<-e.treeLock
+	default:
+	}
+
+	select {
+	case e.completionLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, errors.WithStack(ctx.Err())
+	}
 
-	// Close the pipes for all the IO copies to finish
-	_ = stderrW.Close()
-	_ = stdoutW.Close()
-	wg.Wait()
+	if cmd := findCommand(e.completionCmd, args); cmd != nil {
+		resetCompletionFlags(cmd)
+	}
+	return e.completionCmd, func() { <-e.completionLock }, nil
+}
 
-	return errors.WithStack(err)
+// findCommand returns the command in the tree which the args would execute, or nil if there is none
+func findCommand(rootCmd *cobra.Command, args []string) *cobra.Command {
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil {
+		return nil
+	}
+	return cmd
 }
 
-// addDefaultShellCommands adds the default shell commands to the root command
-func addDefaultShellCommands(rootCmd *cobra.Command) {
-	rootCmd.AddCommand(&cobra.Command{
-		Use:     "exit",
-		Short:   "Exit the shell",
-		Aliases: []string{"quit"},
-		Args:    cobra.NoArgs,
-		RunE: func(cmd *cobra.Command, args []string) error {
-			// no-op which is needed otherwise Cobra won't list the help for it
-			// when the user runs `help exit`
-			//
-			// However the shell will intercept this command and exit the shell
-			// before running this function
-			return nil
-		},
-	})
+// captureProcessOutput replaces os.Stdout and os.Stderr with pipes which are copied into
+// the given writers, returning the write ends of the pipes for the command to use.
+//
+// If another command is capturing the output, this waits for it to finish or returns the
+// context's error if the context is done first.
+//
+// The returned restore function must be called once the command has finished, which
+// waits for all the output to be copied before restoring os.Stdout and os.Stderr.
+func captureProcessOutput(ctx context.Context, stdout io.Writer, stderr io.Writer) (cmdOut io.Writer, cmdErr io.Writer, restore func(), err error) {
+	select {
+	case stdoutCaptureLock <- struct{}{}:
+	case <-ctx.Done():
+		return nil, nil, nil, errors.WithStack(ctx.Err())
+	}
+	originalStdOut := os.Stdout
+	originalStdErr := os.Stderr
+
+	// Set up our stdout and stderr pipes
+	stdoutR, stdoutW, err := os.Pipe()
+	if err != nil {
+		<-stdoutCaptureLock
+		return nil, nil, nil, errors.Wrap(err, "unable to create pipe to capture stdout")
+	}
+	stderrR, stderrW, err := os.Pipe()
+	if err != nil {
+		_ = stdoutR.Close()
+		_ = stdoutW.Close()
+		<-stdoutCaptureLock
+		return nil, nil, nil, errors.Wrap(err, "unable to create pipe to capture stderr")
+	}
+	stdoutSwapMu.Lock()
+	os.Stdout = stdoutW
+	os.Stderr = stderrW
+	stdoutSwapMu.Unlock()
+
+	// Copy the output to the correct places
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		_, _ = io.Copy(stdout, stdoutR)
+		_ = stdoutR.Close()
+		wg.Done()
+	}()
+	go func() {
+		_, _ = io.Copy(stderr, stderrR)
+		_ = stderrR.Close()
+		wg.Done()
+	}()
+
+	return stdoutW, stderrW, func() {
+		// Close the pipes for all the IO copies to finish
+		_ = stderrW.Close()
+		_ = stdoutW.Close()
+		wg.Wait()
+
+		stdoutSwapMu.Lock()
+		os.Stdout = originalStdOut
+		os.Stderr = originalStdErr
+		stdoutSwapMu.Unlock()
+		<-stdoutCaptureLock
+	}, nil
 }
@@ -0,0 +1,113 @@
+package cobrautils
+
+import (
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// cloneForCompletion returns a copy of the command tree for autocomplete requests to use
+// while the tree is being used by running commands.
+//
+// The copy shares everything needed to complete a command apart from the flags, which are
+// given their own values, so parsing them does not change the flags of the running commands.
+// As cobra keys the functions registered with [cobra.Command.RegisterFlagCompletionFunc] by
+// the original flags, they are not available on the copy.
+func cloneForCompletion(cmd *cobra.Command) *cobra.Command {
+	clone := *cmd
+	clone.ResetCommands()
+	clone.ResetFlags()
+
+	cmd.LocalNonPersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		clone.Flags().AddFlag(cloneFlag(flag))
+	})
+	cmd.PersistentFlags().VisitAll(func(flag *pflag.Flag) {
+		clone.PersistentFlags().AddFlag(cloneFlag(flag))
+	})
+
+	for _, subCmd := range cmd.Commands() {
+		clone.AddCommand(cloneForCompletion(subCmd))
+	}
+
+	return &clone
+}
+
+// cloneFlag returns a copy of the flag with its own value, which starts as the default value
+func cloneFlag(flag *pflag.Flag) *pflag.Flag {
+	clone := *flag
+	clone.Changed = false
+
+	value := &completionValue{typ: flag.Value.Type()}
+	_, value.slice = flag.Value.(pflag.SliceValue)
+	value.reset(flag.DefValue)
+	clone.Value = value
+
+	return &clone
+}
+
+// resetCompletionFlags sets the copied flags of cmd back to their default values,
+// so nothing parsed by a previous autocomplete request is left behind
+func resetCompletionFlags(cmd *cobra.Command) {
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		if value, ok := flag.Value.(*completionValue); ok {
+			value.reset(flag.DefValue)
+			flag.Changed = false
+		}
+	})
+}
+
+// completionValue is the value of a copied flag. It reports the same type as the original
+// value, so it can still be read with the getters on the flag set, such as GetString.
+type completionValue struct {
+	typ     string
+	slice   bool
+	changed bool
+	values  []string
+}
+
+var _ pflag.Value = (*completionValue)(nil)
+
+// String implements pflag.Value
+func (v *completionValue) String() string {
+	if v.slice {
+		return "[" + strings.Join(v.values, ",") + "]"
+	}
+	if len(v.values) == 0 {
+		return ""
+	}
+	return v.values[0]
+}
+
+// Set implements pflag.Value
+func (v *completionValue) Set(value string) error {
+	switch {
+	case !v.slice:
+		v.values = []string{value}
+	case !v.changed:
+		// Like pflag's slices, the first value given replaces the default
+		v.values = strings.Split(value, ",")
+	default:
+		v.values = append(v.values, strings.Split(value, ",")...)
+	}
+
+	v.changed = true
+	return nil
+}
+
+// Type implements pflag.Value
+func (v *completionValue) Type() string {
+	return v.typ
+}
+
+// reset sets the value back to the given default value
+func (v *completionValue) reset(defValue string) {
+	v.changed = false
+	v.values = nil
+
+	if !v.slice {
+		v.values = []string{defValue}
+	} else if defValue = strings.Trim(defValue, "[]"); defValue != "" {
+		v.values = strings.Split(defValue, ",")
+	}
+}
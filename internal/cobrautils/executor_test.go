@@ -0,0 +1,364 @@
+package cobrautils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// testRootCmd returns a command tree with a `wait` command which waits for
+// the barrier before writing its arguments, and a `print` command which
+// writes directly to os.Stdout
+func testRootCmd(barrier *sync.WaitGroup) *cobra.Command {
+	rootCmd := &cobra.Command{}
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "wait",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			barrier.Done()
+			barrier.Wait()
+
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), strings.Join(args, " "))
+			return err
+		},
+	})
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "print",
+		Run: func(cmd *cobra.Command, args []string) {
+			fmt.Println(strings.Join(args, " "))
+		},
+	})
+	return rootCmd
+}
+
+func TestExecutorsRunConcurrently(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	first := NewExecutor(testRootCmd(&barrier), false)
+	second := NewExecutor(testRootCmd(&barrier), false)
+
+	var firstOut, secondOut bytes.Buffer
+	errs := make(chan error, 2)
+	go func() {
+		errs <- first.ExecuteArgs(context.Background(), []string{"wait", "first"}, nil, &firstOut, io.Discard)
+	}()
+	go func() {
+		errs <- second.ExecuteArgs(context.Background(), []string{"wait", "second"}, nil, &secondOut, io.Discard)
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected commands from different executors to run concurrently")
+		}
+	}
+
+	if firstOut.String() != "first\n" || secondOut.String() != "second\n" {
+		t.Errorf("expected the output of each command to be kept separate but got %q and %q", firstOut.String(), secondOut.String())
+	}
+}
+
+// waitUntilRunning waits until a command is running on the executor's command tree
+func waitUntilRunning(e *Executor) {
+	for {
+		e.running.mu.Lock()
+		running := len(e.running.cmds)
+		e.running.mu.Unlock()
+
+		if running > 0 {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExecutorWaitsForRunningCommand(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	executor := NewExecutor(testRootCmd(&barrier), false)
+
+	// This will block until the barrier is released
+	done := make(chan error)
+	go func() {
+		done <- executor.ExecuteArgs(context.Background(), []string{"wait"}, nil, io.Discard, io.Discard)
+	}()
+	waitUntilRunning(executor)
+
+	// Other commands can run on the tree at the same time
+	var stdout bytes.Buffer
+	if err := executor.ExecuteArgs(context.Background(), []string{"print"}, nil, &stdout, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := executor.ExecuteArgs(ctx, []string{"wait"}, nil, io.Discard, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error while waiting for the running command but got %v", err)
+	}
+
+	barrier.Done()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExecutorWaitsForSharedFlags(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	rootCmd := testRootCmd(&barrier)
+	verbose := rootCmd.PersistentFlags().Bool("verbose", false, "")
+	executor := NewExecutor(rootCmd, false)
+
+	done := make(chan error)
+	go func() {
+		done <- executor.ExecuteArgs(context.Background(), []string{"wait", "--verbose"}, nil, io.Discard, io.Discard)
+	}()
+	waitUntilRunning(executor)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := executor.ExecuteArgs(ctx, []string{"print"}, nil, io.Discard, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error while waiting for the command sharing a flag but got %v", err)
+	}
+	if !*verbose {
+		t.Errorf("expected the flag of the running command to be kept but it was reset")
+	}
+
+	barrier.Done()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := executor.ExecuteArgs(context.Background(), []string{"print"}, nil, io.Discard, io.Discard); err != nil {
+		t.Fatalf("expected the command to run once the other had finished but got %v", err)
+	}
+}
+
+func TestExecutorCompletesWhileCommandRuns(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	rootCmd := testRootCmd(&barrier)
+	env := rootCmd.PersistentFlags().String("env", "dev", "")
+
+	printCmd, _, _ := rootCmd.Find([]string{"print"})
+	printCmd.ValidArgsFunction = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		env, err := cmd.Flags().GetString("env")
+		if err != nil {
+			return nil, cobra.ShellCompDirectiveError
+		}
+		return []string{env}, cobra.ShellCompDirectiveNoFileComp
+	}
+	executor := NewExecutor(rootCmd, false)
+
+	done := make(chan error)
+	go func() {
+		done <- executor.ExecuteArgs(context.Background(), []string{"wait", "--env", "prod"}, nil, io.Discard, io.Discard)
+	}()
+	waitUntilRunning(executor)
+
+	// The completion shares the flag with the running command, but should not wait for it
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	var completions bytes.Buffer
+	if err := executor.ExecuteArgs(ctx, []string{cobra.ShellCompRequestCmd, "print", "--env", "staging", ""}, nil, &completions, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(completions.String(), "staging\n") {
+		t.Errorf("expected the completions to use the flags being completed but got %q", completions.String())
+	}
+	if *env != "prod" {
+		t.Errorf("expected the flag of the running command to be kept but got %q", *env)
+	}
+
+	barrier.Done()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExecutorCaptureStdout(t *testing.T) {
+	var stdout bytes.Buffer
+	if err := NewExecutor(testRootCmd(nil), true).ExecuteArgs(context.Background(), []string{"print", "captured"}, nil, &stdout, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if stdout.String() != "captured\n" {
+		t.Errorf("expected os.Stdout to be captured but got %q", stdout.String())
+	}
+}
+
+func TestExecutorWaitsForCapture(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	capturing := NewExecutor(testRootCmd(&barrier), true)
+	executor := NewExecutor(testRootCmd(nil), true)
+
+	// This will block until the barrier is released, while capturing os.Stdout
+	done := make(chan error)
+	go func() {
+		done <- capturing.ExecuteArgs(context.Background(), []string{"wait"}, nil, io.Discard, io.Discard)
+	}()
+	for len(stdoutCaptureLock) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := executor.ExecuteArgs(ctx, []string{"print"}, nil, io.Discard, io.Discard); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the context error while waiting to capture os.Stdout but got %v", err)
+	}
+
+	// Autocomplete requests are not captured, so don't wait for the other command
+	var completions bytes.Buffer
+	if err := executor.ExecuteArgs(context.Background(), []string{cobra.ShellCompRequestCmd, "pri"}, nil, &completions, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(completions.String(), "print\n") {
+		t.Errorf("expected the completions to be written to stdout but got %q", completions.String())
+	}
+
+	barrier.Done()
+	if err := <-done; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestFactoryExecutor(t *testing.T) {
+	var barrier sync.WaitGroup
+	barrier.Add(2)
+
+	newRootCmd := func() *cobra.Command {
+		rootCmd := testRootCmd(&barrier)
+		greet := &cobra.Command{
+			Use: "greet",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				loud, _ := cmd.Flags().GetBool("loud")
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "loud=%v", loud)
+				return err
+			},
+		}
+		greet.Flags().Bool("loud", false, "")
+		rootCmd.PersistentFlags().String("env", "dev", "")
+		rootCmd.AddCommand(greet)
+		return rootCmd
+	}
+	executor := NewFactoryExecutor(newRootCmd, false)
+
+	// Two commands on the same executor can run at once
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			errs <- executor.ExecuteArgs(context.Background(), []string{"wait"}, nil, io.Discard, io.Discard)
+		}()
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected commands from a factory executor to run concurrently")
+		}
+	}
+
+	// Flags set in one execution don't leak into the next
+	var out bytes.Buffer
+	if err := executor.ExecuteArgs(context.Background(), []string{"greet", "--loud", "--env", "prod"}, nil, &out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+	if err := executor.ExecuteArgs(context.Background(), []string{"greet"}, nil, &out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "loud=false" {
+		t.Errorf("expected the flag to be reset but got %q", out.String())
+	}
+}
+
+func TestExecutorCommandsReplaceBuiltins(t *testing.T) {
+	newRootCmd := func() *cobra.Command {
+		rootCmd := testRootCmd(nil)
+		rootCmd.AddCommand(&cobra.Command{
+			Use: "set",
+			RunE: func(cmd *cobra.Command, args []string) error {
+				_, err := fmt.Fprintf(cmd.OutOrStdout(), "app set %s", strings.Join(args, " "))
+				return err
+			},
+		})
+		rootCmd.AddCommand(&cobra.Command{
+			Use:     "audit",
+			Aliases: []string{"history"},
+			RunE: func(cmd *cobra.Command, args []string) error {
+				_, err := fmt.Fprint(cmd.OutOrStdout(), "app history")
+				return err
+			},
+		})
+		return rootCmd
+	}
+
+	rootCmd := newRootCmd()
+	executors := map[string]*Executor{
+		"shared":  NewExecutor(rootCmd, false),
+		"factory": NewFactoryExecutor(newRootCmd, false),
+	}
+
+	for name, executor := range executors {
+		for line, expected := range map[string]string{"set a b": "app set a b", "history": "app history"} {
+			var out bytes.Buffer
+			if err := executor.RunLine(context.Background(), line, strings.NewReader(""), &out, io.Discard); err != nil {
+				t.Errorf("%s: %s: unexpected error: %v", name, line, err)
+			} else if out.String() != expected {
+				t.Errorf("%s: %s: expected the application's command to run but got %q", name, line, out.String())
+			}
+		}
+
+		if _, found := executor.sessionCommand("vars"); !found {
+			t.Errorf("%s: expected builtins which don't clash to still be run on their own", name)
+		}
+	}
+
+	// The builtins which clash are not added, so are not listed twice in the help
+	counts := make(map[string]int)
+	for _, cmd := range rootCmd.Commands() {
+		counts[cmd.Name()]++
+	}
+	if counts["set"] != 1 || counts["history"] != 0 || counts["vars"] != 1 {
+		t.Errorf("expected only the application's set and history commands but got %v", counts)
+	}
+}
+
+func TestExecutedHook(t *testing.T) {
+	executor := NewExecutor(scriptRootCmd(), false)
+
+	var executed []string
+	ctx := WithExecutedHook(context.Background(), func(cmd *cobra.Command) {
+		executed = append(executed, cmd.Name())
+	})
+
+	if err := executor.ExecuteArgs(ctx, []string{"echo", "hello"}, nil, io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := executor.ExecuteArgs(ctx, []string{"fail"}, nil, io.Discard, io.Discard); err == nil {
+		t.Fatalf("expected an error from the fail command")
+	}
+
+	if strings.Join(executed, ",") != "echo,fail" {
+		t.Errorf("expected the hook to be called with echo and fail but got %q", executed)
+	}
+}
@@ -0,0 +1,112 @@
+package cobrautils
+
+import (
+	"io"
+	"os"
+
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/cockroachdb/errors"
+)
+
+// RedirectResult describes the data which was moved through a redirected stream of a command
+type RedirectResult struct {
+	syntax.Redirect
+	Path  string // The path of the file after variables were expanded
+	Bytes int64  // The number of bytes read from or written to the file
+}
+
+// redirectFile is a file opened for a redirect, which counts
+// the bytes read or written through it
+type redirectFile struct {
+	redirect syntax.Redirect
+	path     string
+	file     *os.File
+	bytes    int64
+}
+
+// Read implements io.Reader
+func (f *redirectFile) Read(p []byte) (n int, err error) {
+	n, err = f.file.Read(p)
+	f.bytes += int64(n)
+	return n, err // not wrapped, as callers compare against io.EOF
+}
+
+// Write implements io.Writer
+func (f *redirectFile) Write(p []byte) (n int, err error) {
+	n, err = f.file.Write(p)
+	f.bytes += int64(n)
+	return n, errors.WithStack(err)
+}
+
+// openRedirect opens the target file of the redirect
+func openRedirect(redirect syntax.Redirect, path string) (*redirectFile, error) {
+	var (
+		file *os.File
+		err  error
+	)
+
+	switch redirect.Kind {
+	case syntax.RedirectInput:
+		file, err = os.Open(path)
+	case syntax.RedirectOutput:
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	case syntax.RedirectAppend:
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	default:
+		return nil, errors.Newf("unknown redirect kind %d", redirect.Kind)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to open %s", path)
+	}
+
+	return &redirectFile{redirect: redirect, path: path, file: file}, nil
+}
+
+// withRedirects opens all the redirects for the command and then calls fn with the
+// streams the command should use, closing the files once fn returns.
+func withRedirects(
+	redirects []syntax.Redirect, expand func(name string) string, in io.Reader, stdout io.Writer, stderr io.Writer,
+	fn func(in io.Reader, stdout io.Writer, stderr io.Writer) error,
+) (results []RedirectResult, err error) {
+	files := make([]*redirectFile, 0, len(redirects))
+	defer func() {
+		for _, file := range files {
+			_ = file.file.Close()
+		}
+	}()
+
+	for _, redirect := range redirects {
+		file, err := openRedirect(redirect, redirect.Target.Expand(expand))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file)
+
+		switch redirect.Fd {
+		case syntax.Stdin:
+			in = file
+		case syntax.Stdout:
+			stdout = file
+		case syntax.Stderr:
+			stderr = file
+		}
+	}
+
+	err = fn(in, stdout, stderr)
+
+	results = make([]RedirectResult, len(files))
+	for i, file := range files {
+		results[i] = RedirectResult{Redirect: file.redirect, Path: file.path, Bytes: file.bytes}
+	}
+	return results, err
+}
+
+// redirectsInput returns true if any of the redirects replace the input of the command
+func redirectsInput(redirects []syntax.Redirect) bool {
+	for _, redirect := range redirects {
+		if redirect.Fd == syntax.Stdin {
+			return true
+		}
+	}
+	return false
+}
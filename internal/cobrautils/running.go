@@ -0,0 +1,125 @@
+package cobrautils
+
+import (
+	"io"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// runningCommands tracks the commands on a shared command tree which are running
+// without holding the tree lock, along with the flags they were parsed into
+type runningCommands struct {
+	mu       sync.Mutex
+	cmds     map[*cobra.Command]bool
+	flags    map[*pflag.Flag]bool
+	finished chan struct{} // Closed and replaced each time a command finishes
+}
+
+// start marks the command and all of its flags as running
+func (r *runningCommands) start(cmd *cobra.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cmds == nil {
+		r.cmds = make(map[*cobra.Command]bool)
+		r.flags = make(map[*pflag.Flag]bool)
+		r.finished = make(chan struct{})
+	}
+
+	r.cmds[cmd] = true
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		r.flags[flag] = true
+	})
+}
+
+// finish marks the command and its flags as no longer running
+func (r *runningCommands) finish(cmd *cobra.Command) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.cmds, cmd)
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		delete(r.flags, flag)
+	})
+
+	close(r.finished)
+	r.finished = make(chan struct{})
+}
+
+// conflict returns a channel which is closed when the next running command finishes if cmd
+// can not be executed yet, as it or one of its parents is running, or it shares a flag with a
+// running command. If cmd can be executed, nil is returned.
+//
+// A nil cmd is treated as the root command, which every execution uses.
+func (r *runningCommands) conflict(rootCmd *cobra.Command, cmd *cobra.Command) <-chan struct{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.cmds) == 0 {
+		return nil
+	}
+
+	if cmd == nil {
+		cmd = rootCmd
+	}
+	for c := cmd; c != nil; c = c.Parent() {
+		if r.cmds[c] {
+			return r.finished
+		}
+	}
+
+	// Merge in the persistent flags of the parents, which cobra would do when executing
+	_ = cmd.InheritedFlags()
+
+	sharesFlag := false
+	cmd.Flags().VisitAll(func(flag *pflag.Flag) {
+		sharesFlag = sharesFlag || r.flags[flag]
+	})
+	if sharesFlag {
+		return r.finished
+	}
+	return nil
+}
+
+// releaseTreeWhileRunning replaces the run function of cmd, so that while it is running the
+// tree lock is released and other commands can be executed on the tree. The returned
+// function restores cmd and must be called while holding the tree lock once cobra has
+// finished executing it.
+func (e *Executor) releaseTreeWhileRunning(cmd *cobra.Command, in io.Reader, stdout io.Writer, stderr io.Writer) (restore func()) {
+	run, runE := cmd.Run, cmd.RunE
+	if run == nil && runE == nil {
+		return func() {}
+	}
+
+	started := false
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		// The next command executed will replace the streams on the root command, so this command needs its own
+		cmd.SetIn(in)
+		cmd.SetOut(stdout)
+		cmd.SetErr(stderr)
+
+		e.running.start(cmd)
+		started = true
+
+		<-e.treeLock
+		defer func() { e.treeLock <- struct{}{} }()
+
+		if runE != nil {
+			return runE(cmd, args)
+		}
+		run(cmd, args)
+		return nil
+	}
+
+	return func() {
+		cmd.RunE = runE
+		if started {
+			cmd.SetIn(nil)
+			cmd.SetOut(nil)
+			cmd.SetErr(nil)
+			e.running.finish(cmd)
+		}
+	}
+}
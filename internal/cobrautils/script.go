@@ -0,0 +1,176 @@
+package cobrautils
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/cockroachdb/errors"
+)
+
+// maxScriptDepth is the maximum number of scripts which can be sourced within each other,
+// which stops a script which sources itself from running forever
+const maxScriptDepth = 16
+
+// ScriptOptions configures how a script is run by [Executor.RunScript]
+type ScriptOptions struct {
+	// ContinueOnError will cause the script to continue running
+	// the following lines after a line fails
+	ContinueOnError bool
+
+	// ReportError if set is called with the error of each line which fails
+	ReportError func(err error)
+}
+
+// RunScript reads lines from the script and runs them one after another in the same way as the
+// interactive shell, writing the output of the commands to stdout and stderr. A line which ends
+// inside quotes or with a backslash is continued on the next line, and a line of `exit`
+// stops the script.
+//
+// By default the script stops at the first line which fails, returning the error from that line,
+// otherwise if [ScriptOptions.ContinueOnError] is set an error is returned once the script has
+// finished if any of the lines failed.
+//
+// Background jobs are not supported within scripts.
+func (e *Executor) RunScript(ctx context.Context, script io.Reader, stdout io.Writer, stderr io.Writer, opts ScriptOptions) error {
+	depth, _ := ctx.Value(scriptDepthKey{}).(int)
+	if depth >= maxScriptDepth {
+		return errors.Newf("scripts nested more than %d deep", maxScriptDepth)
+	}
+	ctx = context.WithValue(ctx, scriptDepthKey{}, depth+1)
+
+	s := session.FromContext(ctx)
+
+	var (
+		scanner   = bufio.NewScanner(script)
+		lineNum   int
+		startLine int
+		pending   string
+		failed    int
+	)
+
+	for scanner.Scan() {
+		lineNum++
+		if pending == "" {
+			startLine = lineNum
+			pending = scanner.Text()
+		} else {
+			pending += "\n" + scanner.Text()
+		}
+
+		list, err := syntax.Parse(pending)
+		if errors.Is(err, syntax.ErrUnterminatedQuote) || errors.Is(err, syntax.ErrTrailingEscape) {
+			// The line continues on the next line
+			continue
+		}
+		line := pending
+		pending = ""
+
+		// Like the interactive shell, exit stops the script
+		if trimmed := strings.TrimSpace(line); trimmed == "exit" || trimmed == "quit" {
+			break
+		}
+
+		if err == nil {
+			// Commands in a script can not read from the terminal, so are given an empty input
+			err = e.runList(ctx, list, strings.NewReader(""), stdout, stderr)
+		} else {
+			err = errors.Wrap(err, "unable to parse command")
+			if s != nil {
+				s.SetLastResult(err)
+			}
+		}
+
+		if err != nil {
+			err = errors.Wrapf(err, "line %d: %s", startLine, firstLine(line))
+			if opts.ReportError != nil {
+				opts.ReportError(err)
+			}
+
+			if !opts.ContinueOnError {
+				return err
+			}
+			failed++
+		}
+
+		if ctx.Err() != nil {
+			return errors.WithStack(ctx.Err())
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "unable to read script")
+	}
+
+	if pending != "" {
+		_, err := syntax.Parse(pending)
+		err = errors.Wrapf(err, "line %d: %s", startLine, firstLine(pending))
+		if opts.ReportError != nil {
+			opts.ReportError(err)
+		}
+		return err
+	}
+
+	if failed > 0 {
+		return errors.Newf("%d of the lines in the script failed", failed)
+	}
+	return nil
+}
+
+// RunLine runs a single line without the interactive shell in the same way as a line of
+// a script run by [Executor.RunScript], returning the error of the first command which fails.
+//
+// Each command in the line is given in as its input, unless its input is redirected.
+func (e *Executor) RunLine(ctx context.Context, line string, in io.Reader, stdout io.Writer, stderr io.Writer) error {
+	list, err := syntax.Parse(line)
+	if err != nil {
+		err = errors.Wrap(err, "unable to parse command")
+		if s := session.FromContext(ctx); s != nil {
+			s.SetLastResult(err)
+		}
+		return err
+	}
+
+	return e.runList(ctx, list, in, stdout, stderr)
+}
+
+// runList runs each of the pipelines in the list, stopping if
+// the last pipeline run in any of the items fails
+func (e *Executor) runList(ctx context.Context, list syntax.List, in io.Reader, stdout io.Writer, stderr io.Writer) error {
+	s := session.FromContext(ctx)
+
+	for _, item := range list.Items {
+		if item.Background {
+			return errors.Newf("unable to run `%s` in the background: background jobs are not supported in scripts", item.Source)
+		}
+
+		var err error
+		for _, pipeline := range item.Pipelines {
+			if !pipeline.Condition.ShouldRun(err == nil) {
+				continue
+			}
+
+			_, err = e.ExecutePipeline(ctx, pipeline, in, stdout, stderr)
+			if s != nil {
+				s.SetLastResult(err)
+			}
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// firstLine returns the first line of a command which may span multiple lines
+func firstLine(line string) string {
+	if first, _, found := strings.Cut(line, "\n"); found {
+		return first + " ..."
+	}
+	return line
+}
+
+type scriptDepthKey struct{}
@@ -0,0 +1,158 @@
+package cobrautils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// scriptRootCmd returns a command tree with an `echo` command which writes its
+// arguments and a `fail` command which always returns an error
+func scriptRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{}
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "echo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), strings.Join(args, " "))
+			return err
+		},
+	})
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "fail",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("failed")
+		},
+	})
+	return rootCmd
+}
+
+func TestRunScript(t *testing.T) {
+	tests := []struct {
+		name            string
+		script          string
+		continueOnError bool
+		expected        string
+		reported        []string
+		err             string
+	}{
+		{
+			name:     "runs each line",
+			script:   "echo a\n\n# a comment\necho b # with a comment\n",
+			expected: "a\nb\n",
+		},
+		{
+			name:     "shares variables",
+			script:   "set name bob\necho hello $name",
+			expected: "hello bob\n",
+		},
+		{
+			name:     "continues quoted lines",
+			script:   "echo \"a\nb\"\necho c \\\nd",
+			expected: "a\nb\nc d\n",
+		},
+		{
+			name:     "stops on the first error",
+			script:   "echo a\nfail\necho b",
+			expected: "a\n",
+			reported: []string{"line 2: fail: failed"},
+			err:      "line 2: fail: failed",
+		},
+		{
+			name:            "continues on error",
+			script:          "fail\necho a\nfail || echo b\nfail",
+			continueOnError: true,
+			expected:        "a\nb\n",
+			reported:        []string{"line 1: fail: failed", "line 4: fail: failed"},
+			err:             "2 of the lines in the script failed",
+		},
+		{
+			name:     "stops on exit",
+			script:   "echo a\nexit\necho b",
+			expected: "a\n",
+		},
+		{
+			name:     "rejects background jobs",
+			script:   "echo a &",
+			reported: []string{"line 1: echo a &: unable to run `echo a` in the background: background jobs are not supported in scripts"},
+			err:      "line 1: echo a &: unable to run `echo a` in the background: background jobs are not supported in scripts",
+		},
+		{
+			name:     "reports unterminated quotes",
+			script:   "echo a\necho \"b",
+			expected: "a\n",
+			reported: []string{"line 2: echo \"b: unterminated quoted string"},
+			err:      "line 2: echo \"b: unterminated quoted string",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			executor := NewExecutor(scriptRootCmd(), false)
+			ctx := session.NewContext(context.Background(), session.New())
+
+			var reported []string
+			var stdout bytes.Buffer
+			err := executor.RunScript(ctx, strings.NewReader(test.script), &stdout, &stdout, ScriptOptions{
+				ContinueOnError: test.continueOnError,
+				ReportError:     func(err error) { reported = append(reported, err.Error()) },
+			})
+
+			if test.err == "" && err != nil {
+				t.Errorf("expected no error but got %v", err)
+			} else if test.err != "" && (err == nil || err.Error() != test.err) {
+				t.Errorf("expected error %q but got %v", test.err, err)
+			}
+
+			if got := stdout.String(); got != test.expected {
+				t.Errorf("expected output %q but got %q", test.expected, got)
+			}
+
+			if strings.Join(reported, "\n") != strings.Join(test.reported, "\n") {
+				t.Errorf("expected reported errors %q but got %q", test.reported, reported)
+			}
+		})
+	}
+}
+
+func TestSourceCommand(t *testing.T) {
+	dir := t.TempDir()
+	inner := filepath.Join(dir, "inner.txt")
+	outer := filepath.Join(dir, "outer.txt")
+	loop := filepath.Join(dir, "loop.txt")
+
+	files := map[string]string{
+		inner: "set name bob\necho inner",
+		outer: fmt.Sprintf("echo outer\nsource %s\necho hello $name", inner),
+		loop:  fmt.Sprintf("source %s", loop),
+	}
+	for file, contents := range files {
+		if err := os.WriteFile(file, []byte(contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	executor := NewExecutor(scriptRootCmd(), false)
+	ctx := session.NewContext(context.Background(), session.New())
+
+	var stdout bytes.Buffer
+	err := executor.RunScript(ctx, strings.NewReader("source "+outer), &stdout, &stdout, ScriptOptions{})
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+	if expected := "outer\ninner\nhello bob\n"; stdout.String() != expected {
+		t.Errorf("expected output %q but got %q", expected, stdout.String())
+	}
+
+	err = executor.RunScript(ctx, strings.NewReader("source "+loop), &stdout, &stdout, ScriptOptions{})
+	if err == nil || !strings.Contains(err.Error(), "scripts nested more than 16 deep") {
+		t.Errorf("expected the nesting limit error but got %v", err)
+	}
+}
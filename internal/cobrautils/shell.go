@@ -0,0 +1,299 @@
+package cobrautils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	"github.com/cockroachdb/errors"
+	"github.com/rs/xid"
+	"github.com/spf13/cobra"
+)
+
+// Shell is the interactive shell the builtin commands are being run within, giving
+// them access to the parts of the shell which live outside the [session.Session]
+type Shell interface {
+	// HistoryItems returns a copy of the items in the shell's history, oldest first
+	HistoryItems(ctx context.Context) ([]history.Item, error)
+
+	// ClearHistory removes all the items from the shell's history, apart from commands still running
+	ClearHistory(ctx context.Context) error
+
+	// RemoveHistoryItem removes the item with the given ID from the shell's history
+	RemoveHistoryItem(ctx context.Context, id xid.ID) error
+
+	// ErrorText renders the error, including its stack trace, as plain text
+	ErrorText(err error) string
+
+	// CopyToClipboard copies the text to the clipboard of the terminal the shell is running in
+	CopyToClipboard(ctx context.Context, text string) error
+
+	// ExportTranscript writes a transcript of the commands run in the session to w in the given format
+	ExportTranscript(ctx context.Context, w io.Writer, format history.ExportFormat) error
+
+	// Replay plays the recording back on the whole screen, at speed times the speed it was
+	// recorded at, waiting until the user stops it
+	Replay(ctx context.Context, title string, recording *asciicast.Recording, speed float64) error
+}
+
+type shellKey struct{}
+
+// WithShell returns a context carrying the interactive shell the commands are being run within
+func WithShell(ctx context.Context, shell Shell) context.Context {
+	return context.WithValue(ctx, shellKey{}, shell)
+}
+
+// shellFor returns the interactive shell the command is being run within
+func shellFor(cmd *cobra.Command) (Shell, error) {
+	shell, _ := cmd.Context().Value(shellKey{}).(Shell)
+	if shell == nil {
+		return nil, errors.Newf("%s can only be run within the interactive shell", cmd.Name())
+	}
+	return shell, nil
+}
+
+type pipedInputKey struct{}
+
+// hasPipedInput returns true if the input of the command is the output of the previous
+// command in the pipeline or a file, rather than what the user types
+func hasPipedInput(cmd *cobra.Command) bool {
+	piped, _ := cmd.Context().Value(pipedInputKey{}).(bool)
+	return piped
+}
+
+// lastFinishedItem returns the most recent item in the history which has finished
+// and matches the filter, ignoring the items of the commands still running
+func lastFinishedItem(items []history.Item, filter func(item history.Item) bool) (history.Item, bool) {
+	for i := len(items) - 1; i >= 0; i-- {
+		item := items[i]
+		if item.Status > history.RunningStatus && filter(item) {
+			return item, true
+		}
+	}
+	return history.Item{}, false
+}
+
+func copyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "copy [text...]",
+		Short: "Copy to the clipboard",
+		Long: "Copy text to the clipboard of the terminal the shell is running in.\n\n" +
+			"If text is given it is copied, if the output of another command is piped in (`report | copy`) that " +
+			"output is copied, otherwise the output of the last command is copied. " +
+			"Use --command to copy the last command run, or --error to copy the last error with its stack trace.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, err := shellFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			copyCommand, _ := cmd.Flags().GetBool("command")
+			copyError, _ := cmd.Flags().GetBool("error")
+
+			var text string
+			switch {
+			case len(args) > 0:
+				text = strings.Join(args, " ")
+
+			case hasPipedInput(cmd):
+				input, err := io.ReadAll(cmd.InOrStdin())
+				if err != nil {
+					return errors.Wrap(err, "unable to read the input")
+				}
+				text = strings.TrimSuffix(string(input), "\n")
+
+			default:
+				items, err := shell.HistoryItems(cmd.Context())
+				if err != nil {
+					return err
+				}
+
+				switch {
+				case copyError:
+					item, found := lastFinishedItem(items, func(item history.Item) bool { return item.Error != nil })
+					if !found {
+						return errors.New("no command has returned an error")
+					}
+					text = shell.ErrorText(item.Error)
+
+				case copyCommand:
+					item, found := lastFinishedItem(items, func(item history.Item) bool { return item.ItemType == history.Command })
+					if !found {
+						return errors.New("no command has been run")
+					}
+					text = item.Line
+
+				default:
+					item, found := lastFinishedItem(items, func(item history.Item) bool { return item.Output != "" })
+					if !found {
+						return errors.New("no command has any output to copy")
+					}
+					text = item.Output
+				}
+			}
+
+			return shell.CopyToClipboard(cmd.Context(), text)
+		},
+	}
+	cmd.Flags().BoolP("command", "c", false, "copy the last command run")
+	cmd.Flags().BoolP("error", "e", false, "copy the last error returned by a command")
+	cmd.MarkFlagsMutuallyExclusive("command", "error")
+
+	return cmd
+}
+
+func exportCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export [file]",
+		Short: "Export a transcript of the session",
+		Long: "Export a transcript of the commands run in this session, with their output and errors.\n\n" +
+			"The transcript is written to the file if one is given, otherwise it is output. " +
+			"The format is chosen from the extension of the file (.md, .html or .txt) unless --format is given.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, err := shellFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			format := history.TextFormat
+			if len(args) > 0 {
+				format = history.ExportFormatForPath(args[0])
+			}
+			if name, _ := cmd.Flags().GetString("format"); name != "" {
+				format, err = history.ParseExportFormat(name)
+				if err != nil {
+					return err
+				}
+			}
+
+			if len(args) == 0 {
+				return shell.ExportTranscript(cmd.Context(), cmd.OutOrStdout(), format)
+			}
+
+			file, err := os.Create(args[0])
+			if err != nil {
+				return errors.Wrap(err, "unable to create the transcript file")
+			}
+			defer func() { _ = file.Close() }()
+
+			if err := shell.ExportTranscript(cmd.Context(), file, format); err != nil {
+				return err
+			}
+			if err := file.Close(); err != nil {
+				return errors.Wrap(err, "unable to write the transcript file")
+			}
+
+			_, _ = fmt.Fprintf(cmd.OutOrStdout(), "exported the transcript to %s\n", args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringP("format", "f", "", "the format to export as: markdown, html or text")
+
+	return cmd
+}
+
+func replayCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Replay a recording of a shell session",
+		Long: "Play back a recording of a terminal session in the asciicast v2 format, such as one " +
+			"recorded by the shell or asciinema.\n\n" +
+			"While replaying, space pauses and resumes, + and - change the speed, home restarts, " +
+			"end skips to the end and q stops the replay.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, err := shellFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			speed, _ := cmd.Flags().GetFloat64("speed")
+			if speed <= 0 {
+				return errors.Newf("the speed must be greater than 0, got %v", speed)
+			}
+
+			file, err := os.Open(args[0])
+			if err != nil {
+				return errors.Wrap(err, "unable to open the recording")
+			}
+			defer func() { _ = file.Close() }()
+
+			recording, err := asciicast.Read(file)
+			if err != nil {
+				return err
+			}
+
+			title := recording.Header.Title
+			if title == "" {
+				title = filepath.Base(args[0])
+			}
+
+			return shell.Replay(cmd.Context(), title, recording, speed)
+		},
+	}
+	cmd.Flags().Float64P("speed", "s", 1, "how many times faster than it was recorded to play the recording")
+
+	return cmd
+}
+
+func historyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "history [filter]",
+		Short: "List the commands in the history",
+		Long: "List the commands in the history, numbered from the oldest, optionally only those containing the filter.\n\n" +
+			"The numbers can be used to run a command again with `!n`, or to delete it with `history -d n`. " +
+			"Use --clear to remove everything from the history.",
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shell, err := shellFor(cmd)
+			if err != nil {
+				return err
+			}
+
+			if clearHistory, _ := cmd.Flags().GetBool("clear"); clearHistory {
+				return shell.ClearHistory(cmd.Context())
+			}
+
+			items, err := shell.HistoryItems(cmd.Context())
+			if err != nil {
+				return err
+			}
+			commands := history.Commands(items)
+
+			if cmd.Flags().Changed("delete") {
+				n, _ := cmd.Flags().GetInt("delete")
+				if n < 1 || n > len(commands) {
+					return errors.Newf("history position %d out of range, expected 1 to %d", n, len(commands))
+				}
+				return shell.RemoveHistoryItem(cmd.Context(), commands[n-1].ID)
+			}
+
+			var filter string
+			if len(args) > 0 {
+				filter = strings.ToLower(args[0])
+			}
+
+			// The numbers are left aligned, as leading whitespace is trimmed from the output
+			out := cmd.OutOrStdout()
+			width := len(strconv.Itoa(len(commands)))
+			for i, command := range commands {
+				if strings.Contains(strings.ToLower(command.Line), filter) {
+					_, _ = fmt.Fprintf(out, "%-*d  %s\n", width, i+1, command.Line)
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().BoolP("clear", "c", false, "remove everything from the history")
+	cmd.Flags().IntP("delete", "d", 0, "delete the command with the given number from the history")
+	cmd.MarkFlagsMutuallyExclusive("clear", "delete")
+
+	return cmd
+}
@@ -0,0 +1,182 @@
+package cobrautils
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	"github.com/cockroachdb/errors"
+	"github.com/rs/xid"
+)
+
+// testShell is a [Shell] with a fixed history, which records what is copied to the clipboard and replayed
+type testShell struct {
+	items    []history.Item
+	copied   []string
+	replayed []*asciicast.Recording
+}
+
+func (s *testShell) HistoryItems(ctx context.Context) ([]history.Item, error) {
+	return s.items, nil
+}
+
+func (s *testShell) ClearHistory(ctx context.Context) error {
+	s.items = nil
+	return nil
+}
+
+func (s *testShell) RemoveHistoryItem(ctx context.Context, id xid.ID) error {
+	for i, item := range s.items {
+		if item.ID == id {
+			s.items = append(s.items[:i:i], s.items[i+1:]...)
+		}
+	}
+	return nil
+}
+
+func (s *testShell) ErrorText(err error) string {
+	return "error text: " + err.Error()
+}
+
+func (s *testShell) CopyToClipboard(ctx context.Context, text string) error {
+	s.copied = append(s.copied, text)
+	return nil
+}
+
+func (s *testShell) ExportTranscript(ctx context.Context, w io.Writer, format history.ExportFormat) error {
+	return history.ExportItems(w, config.Default(), s.items, format)
+}
+
+func (s *testShell) Replay(ctx context.Context, title string, recording *asciicast.Recording, speed float64) error {
+	s.replayed = append(s.replayed, recording)
+	return nil
+}
+
+func TestCopyCommand(t *testing.T) {
+	withOutput := history.NewItem("", "echo hello", history.SuccessStatus)
+	withOutput.Output = "hello"
+	withError := history.NewItem("", "fail", history.ErrorStatus)
+	withError.Error = errors.New("failed")
+	running := history.NewItem("", "copy", history.RunningStatus)
+
+	shell := &testShell{items: []history.Item{withOutput, withError, running}}
+	executor := NewExecutor(scriptRootCmd(), false)
+	ctx := WithShell(context.Background(), shell)
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "copy", expected: "hello"},
+		{line: "copy --command", expected: "fail"},
+		{line: "copy -e", expected: "error text: failed"},
+		{line: "copy some text", expected: "some text"},
+		{line: "echo piped | copy", expected: "piped"},
+	}
+
+	for _, test := range tests {
+		shell.copied = nil
+		if err := executor.RunLine(ctx, test.line, strings.NewReader(""), io.Discard, io.Discard); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.line, err)
+			continue
+		}
+
+		if len(shell.copied) != 1 || shell.copied[0] != test.expected {
+			t.Errorf("%s: expected %q to be copied but got %q", test.line, test.expected, shell.copied)
+		}
+	}
+
+	// Outside the interactive shell there is no clipboard to copy to
+	if err := executor.RunLine(context.Background(), "copy", strings.NewReader(""), io.Discard, io.Discard); err == nil {
+		t.Errorf("expected an error when not running within the interactive shell")
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	item := history.NewItem("> ", "echo hello", history.SuccessStatus)
+	item.Output = "hello"
+
+	executor := NewExecutor(scriptRootCmd(), false)
+	ctx := WithShell(context.Background(), &testShell{items: []history.Item{item}})
+
+	var out strings.Builder
+	if err := executor.RunLine(ctx, "export", strings.NewReader(""), &out, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "> echo hello\n") {
+		t.Errorf("expected a plain text transcript to be output but got:\n%s", out.String())
+	}
+
+	file := filepath.Join(t.TempDir(), "session.md")
+	if err := executor.RunLine(ctx, "export "+file, strings.NewReader(""), io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	transcript, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("unable to read the transcript: %v", err)
+	}
+	if !strings.Contains(string(transcript), "## `> echo hello`") {
+		t.Errorf("expected a markdown transcript to be written but got:\n%s", transcript)
+	}
+
+	if err := executor.RunLine(ctx, "export --format pdf", strings.NewReader(""), io.Discard, io.Discard); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestHistoryCommand(t *testing.T) {
+	deploy := history.NewItem("> ", "deploy api", history.SuccessStatus)
+	sub := history.NewItem("", "echo one", history.SuccessStatus)
+	sub.ItemType = history.SubCommand
+	status := history.NewItem("> ", "status", history.SuccessStatus)
+	deployWeb := history.NewItem("> ", "Deploy web", history.ErrorStatus)
+
+	shell := &testShell{items: []history.Item{deploy, sub, status, deployWeb}}
+	executor := NewExecutor(scriptRootCmd(), false)
+	ctx := WithShell(context.Background(), shell)
+
+	tests := []struct {
+		line     string
+		expected string
+	}{
+		{line: "history", expected: "1  deploy api\n2  status\n3  Deploy web\n"},
+		{line: "history deploy", expected: "1  deploy api\n3  Deploy web\n"},
+	}
+
+	for _, test := range tests {
+		var out strings.Builder
+		if err := executor.RunLine(ctx, test.line, strings.NewReader(""), &out, io.Discard); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.line, err)
+			continue
+		}
+		if out.String() != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.line, test.expected, out.String())
+		}
+	}
+
+	if err := executor.RunLine(ctx, "history -d 2", strings.NewReader(""), io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shell.items) != 3 || shell.items[2].ID != deployWeb.ID {
+		t.Errorf("expected the second command to be deleted but got %v", shell.items)
+	}
+
+	for _, line := range []string{"history -d 0", "history -d 4"} {
+		if err := executor.RunLine(ctx, line, strings.NewReader(""), io.Discard, io.Discard); err == nil {
+			t.Errorf("%s: expected an error for a position out of range", line)
+		}
+	}
+
+	if err := executor.RunLine(ctx, "history -c", strings.NewReader(""), io.Discard, io.Discard); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(shell.items) != 0 {
+		t.Errorf("expected the history to be cleared but got %v", shell.items)
+	}
+}
@@ -2,9 +2,12 @@ package config
 
 import (
 	"context"
+	"io"
+	"os"
 
 	"github.com/DomBlack/bubble-shell/pkg/config/keymap"
 	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	"github.com/spf13/cobra"
 )
 
 // Config is the configuration for the shell
@@ -37,6 +40,34 @@ type Config struct {
 
 	// PromptFunc is a function that returns the prompt to be used
 	PromptFunc func() string
+
+	// CommandFactory if set is used to create a new command tree
+	// for each command executed and each autocomplete request
+	CommandFactory func() *cobra.Command
+
+	// CaptureStdout will cause anything commands write directly to os.Stdout
+	// or os.Stderr to be captured as part of their output
+	CaptureStdout bool
+
+	// ContinueOnError will cause scripts to continue running
+	// after a line fails rather than stopping
+	ContinueOnError bool
+
+	// Aliases are the default aliases available in the shell,
+	// mapping from the alias name to the command it expands to
+	Aliases map[string]string
+
+	// Output is where the shell is being rendered, which escape sequences
+	// the terminal should act on, such as copying to the clipboard, are written to
+	Output io.Writer
+
+	// RecordingFile if set is the file everything the shell renders
+	// is recorded to, in the asciicast v2 format
+	RecordingFile string
+
+	// ViMode will cause the command input to be edited with vi
+	// style normal, insert and visual modes
+	ViMode bool
 }
 
 // Default returns a default configuration for the shell
@@ -45,6 +76,7 @@ func Default() *Config {
 		HistoryFile:    ".bubble-shell-history",
 		RootContext:    context.Background(),
 		PromptFunc:     func() string { return "> " },
+		Output:         os.Stdout,
 		KeyMap:         keymap.Default,
 		Styles:         styles.Default,
 		MaxStackFrames: 8,
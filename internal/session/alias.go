@@ -0,0 +1,157 @@
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/cockroachdb/errors"
+)
+
+// ValidateAlias checks that the name can be used as an alias, and that
+// the value is one or more words which can be used in place of the name.
+func ValidateAlias(name, value string) error {
+	if name == "" || strings.Contains(name, "=") || syntax.Quote(name) != name {
+		return errors.Newf("invalid alias name %q", name)
+	}
+
+	words, err := syntax.Split(value)
+	if err != nil {
+		return errors.Wrapf(err, "invalid value for alias %q", name)
+	}
+	if len(words) == 0 {
+		return errors.Newf("alias %q must have a value", name)
+	}
+
+	return nil
+}
+
+// Alias returns the value of the alias with the given name
+func (s *Session) Alias(name string) (value string, found bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if value, found := s.aliases[name]; found {
+		return value, true
+	}
+
+	value, found = s.defaultAliases[name]
+	return value, found
+}
+
+// SetDefaultAlias sets an alias provided by the application, which will
+// not be persisted to the alias file and can be overridden by the user.
+func (s *Session) SetDefaultAlias(name, value string) error {
+	if err := ValidateAlias(name, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.defaultAliases[name] = value
+	return nil
+}
+
+// SetAlias sets the alias, saving it to the alias file if one has been loaded
+func (s *Session) SetAlias(name, value string) error {
+	if err := ValidateAlias(name, value); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aliases[name] = value
+	return s.saveAliases()
+}
+
+// RemoveAlias removes the alias, returning false if the alias was not set.
+//
+// Removing an alias provided by the application only lasts for this session.
+func (s *Session) RemoveAlias(name string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, isDefault := s.defaultAliases[name]
+	_, isUser := s.aliases[name]
+	delete(s.defaultAliases, name)
+	delete(s.aliases, name)
+
+	if !isUser {
+		return isDefault, nil
+	}
+	return true, s.saveAliases()
+}
+
+// AliasNames returns the names of all the aliases in sorted order
+func (s *Session) AliasNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.aliases)+len(s.defaultAliases))
+	for name := range s.aliases {
+		names = append(names, name)
+	}
+	for name := range s.defaultAliases {
+		if _, found := s.aliases[name]; !found {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadAliases loads the user defined aliases from the file, and then
+// saves any future changes to the aliases back to that file.
+//
+// It is not an error for the file not to exist yet.
+func (s *Session) LoadAliases(file string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.aliasFile = file
+
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return errors.Wrap(err, "unable to read alias file")
+	}
+
+	var aliases map[string]string
+	if err := json.Unmarshal(bytes, &aliases); err != nil {
+		return errors.Wrap(err, "unable to unmarshal alias file")
+	}
+
+	for name, value := range aliases {
+		// Aliases defined before the file was loaded take priority
+		if _, found := s.aliases[name]; !found {
+			s.aliases[name] = value
+		}
+	}
+
+	return nil
+}
+
+// saveAliases writes the user defined aliases to the alias file
+//
+// The caller must hold the write lock
+func (s *Session) saveAliases() error {
+	if s.aliasFile == "" {
+		return nil
+	}
+
+	bytes, err := json.MarshalIndent(s.aliases, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "unable to marshal aliases")
+	}
+
+	if err := os.WriteFile(s.aliasFile, bytes, 0644); err != nil {
+		return errors.Wrap(err, "unable to write alias file")
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package session
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+// JobState is the state of a background job
+type JobState uint8
+
+const (
+	JobRunning JobState = iota // The job is still running
+	JobDone                    // The job finished successfully
+	JobFailed                  // The job returned an error
+	JobKilled                  // The job was stopped by the user
+)
+
+// String returns the state as shown to users by the `jobs` command
+func (s JobState) String() string {
+	switch s {
+	case JobRunning:
+		return "Running"
+	case JobDone:
+		return "Done"
+	case JobFailed:
+		return "Failed"
+	case JobKilled:
+		return "Killed"
+	default:
+		return "Unknown"
+	}
+}
+
+// Job is a list of commands which is being run in the background
+type Job struct {
+	ID      int       // The number of the job, as used in job specs such as `%1`
+	Line    string    // The commands being run by the job
+	Started time.Time // When the job was started
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	// These fields are guarded by the session's lock
+	state  JobState
+	err    error
+	killed bool
+}
+
+// Done returns a channel which is closed when the job has finished
+func (j *Job) Done() <-chan struct{} {
+	return j.done
+}
+
+// StartJob records a new background job which will be stopped by calling cancel
+func (s *Session) StartJob(line string, cancel context.CancelFunc) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like POSIX shells, job numbers are reused once all the jobs above them have been removed
+	id := 1
+	for _, job := range s.jobs {
+		if job.ID >= id {
+			id = job.ID + 1
+		}
+	}
+
+	job := &Job{
+		ID:      id,
+		Line:    line,
+		Started: time.Now(),
+		cancel:  cancel,
+		done:    make(chan struct{}),
+	}
+	s.jobs = append(s.jobs, job)
+	return job
+}
+
+// FinishJob records that the job has finished with the given error
+func (s *Session) FinishJob(job *Job, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.state != JobRunning {
+		return
+	}
+
+	job.err = err
+	switch {
+	case job.killed:
+		job.state = JobKilled
+	case err != nil:
+		job.state = JobFailed
+	default:
+		job.state = JobDone
+	}
+
+	job.cancel()
+	close(job.done)
+}
+
+// JobState returns the current state of the job, and the error it finished with
+func (s *Session) JobState(job *Job) (JobState, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return job.state, job.err
+}
+
+// KillJob stops the job if it is still running
+func (s *Session) KillJob(job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.state != JobRunning {
+		return errors.Newf("job %d has already finished", job.ID)
+	}
+
+	job.killed = true
+	job.cancel()
+	return nil
+}
+
+// KillAllJobs stops all the jobs which are still running
+func (s *Session) KillAllJobs() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, job := range s.jobs {
+		if job.state == JobRunning {
+			job.killed = true
+			job.cancel()
+		}
+	}
+}
+
+// Jobs returns all the jobs in the session ordered by their ID
+func (s *Session) Jobs() []*Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	jobs := make([]*Job, len(s.jobs))
+	copy(jobs, s.jobs)
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].ID < jobs[j].ID
+	})
+	return jobs
+}
+
+// RemoveJob removes the job from the session
+func (s *Session) RemoveJob(job *Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, existing := range s.jobs {
+		if existing == job {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return
+		}
+	}
+}
+
+// FindJob returns the job for the given job spec, which is either the
+// job number optionally prefixed with `%` (i.e. `%1`), or `%%`, `%+` or
+// an empty string for the most recently started job.
+func (s *Session) FindJob(spec string) (*Job, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch spec {
+	case "", "%", "%%", "%+":
+		// Jobs are stored in the order they were started
+		if len(s.jobs) == 0 {
+			return nil, errors.New("no current job")
+		}
+		return s.jobs[len(s.jobs)-1], nil
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(spec, "%"))
+	if err != nil {
+		return nil, errors.Newf("invalid job spec %q", spec)
+	}
+
+	for _, job := range s.jobs {
+		if job.ID == id {
+			return job, nil
+		}
+	}
+	return nil, errors.Newf("no such job %s", spec)
+}
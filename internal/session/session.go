@@ -0,0 +1,136 @@
+// Package session holds the state of a shell session which is shared
+// between all the commands run within it, such as variables, aliases and
+// background jobs.
+package session
+
+import (
+	"context"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/cockroachdb/errors"
+)
+
+// Session is the state of a single shell session
+//
+// It is safe for concurrent use, as commands are run
+// outside the bubbletea update loop.
+type Session struct {
+	mu         sync.RWMutex
+	vars       map[string]string
+	lastStatus int
+
+	defaultAliases map[string]string // Aliases provided by the application, which are not persisted
+	aliases        map[string]string // Aliases defined by the user
+	aliasFile      string            // The file user defined aliases are persisted to, if any
+
+	jobs []*Job // The background jobs started within the session
+}
+
+// New creates a new empty session
+func New() *Session {
+	return &Session{
+		vars:           make(map[string]string),
+		defaultAliases: make(map[string]string),
+		aliases:        make(map[string]string),
+	}
+}
+
+// Lookup returns the value of the variable with the given name.
+//
+// Variables set within the session take priority, falling back to
+// the environment variables of the process. The special variable `?`
+// is the status of the last command run.
+func (s *Session) Lookup(name string) (value string, found bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name == "?" {
+		return strconv.Itoa(s.lastStatus), true
+	}
+
+	if value, found := s.vars[name]; found {
+		return value, true
+	}
+
+	return os.LookupEnv(name)
+}
+
+// Expand returns the value of the variable with the given name, or
+// an empty string if it is not set.
+//
+// It is intended to be passed to [syntax.Word.Expand].
+func (s *Session) Expand(name string) string {
+	value, _ := s.Lookup(name)
+	return value
+}
+
+// Set sets the variable within the session
+func (s *Session) Set(name, value string) error {
+	if !syntax.IsValidName(name) {
+		return errors.Newf("invalid variable name %q", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.vars[name] = value
+	return nil
+}
+
+// Unset removes the variable from the session, returning
+// false if the variable was not set.
+func (s *Session) Unset(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, found := s.vars[name]
+	delete(s.vars, name)
+	return found
+}
+
+// VarNames returns the names of all the variables set within the session in sorted order
+func (s *Session) VarNames() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := make([]string, 0, len(s.vars))
+	for name := range s.vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetLastResult records the result of the last command run,
+// which is available to later commands as `$?`.
+func (s *Session) SetLastResult(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err != nil {
+		s.lastStatus = 1
+	} else {
+		s.lastStatus = 0
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying the session
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the session from the context, or nil if there is none
+func FromContext(ctx context.Context) *Session {
+	if ctx == nil {
+		return nil
+	}
+
+	s, _ := ctx.Value(contextKey{}).(*Session)
+	return s
+}
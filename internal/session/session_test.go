@@ -0,0 +1,176 @@
+package session
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestSessionVariables(t *testing.T) {
+	t.Setenv("BUBBLE_SHELL_TEST", "from env")
+
+	s := New()
+	if got := s.Expand("BUBBLE_SHELL_TEST"); got != "from env" {
+		t.Errorf("expected environment variable fallback but got %q", got)
+	}
+
+	if err := s.Set("BUBBLE_SHELL_TEST", "from session"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.Set("host", "db-1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := s.Expand("BUBBLE_SHELL_TEST"); got != "from session" {
+		t.Errorf("expected session variable to take priority but got %q", got)
+	}
+
+	if err := s.Set("1x", "invalid"); err == nil {
+		t.Errorf("expected an error setting an invalid variable name")
+	}
+
+	if names := s.VarNames(); !reflect.DeepEqual(names, []string{"BUBBLE_SHELL_TEST", "host"}) {
+		t.Errorf("expected sorted variable names but got %q", names)
+	}
+
+	if !s.Unset("host") || s.Unset("host") {
+		t.Errorf("expected unset to report if the variable was set")
+	}
+	if _, found := s.Lookup("host"); found {
+		t.Errorf("expected host to be unset")
+	}
+}
+
+func TestSessionLastStatus(t *testing.T) {
+	s := New()
+	if got := s.Expand("?"); got != "0" {
+		t.Errorf("expected initial status of 0 but got %q", got)
+	}
+
+	s.SetLastResult(errors.New("failed"))
+	if got := s.Expand("?"); got != "1" {
+		t.Errorf("expected status of 1 after an error but got %q", got)
+	}
+
+	s.SetLastResult(nil)
+	if got := s.Expand("?"); got != "0" {
+		t.Errorf("expected status of 0 after success but got %q", got)
+	}
+}
+
+func TestSessionContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Errorf("expected no session in an empty context")
+	}
+
+	s := New()
+	if FromContext(NewContext(context.Background(), s)) != s {
+		t.Errorf("expected the session to be returned from the context")
+	}
+}
+
+func TestSessionAliases(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "aliases.json")
+
+	s := New()
+	if err := s.SetDefaultAlias("dep", "deploy --env staging"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := s.LoadAliases(file); err != nil {
+		t.Fatalf("unexpected error loading a missing alias file: %v", err)
+	}
+	if err := s.SetAlias("ll", "ls -l"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, invalid := range []struct{ name, value string }{{"a b", "ls"}, {"", "ls"}, {"x", ""}, {"x", "a | b"}} {
+		if err := s.SetAlias(invalid.name, invalid.value); err == nil {
+			t.Errorf("expected an error setting alias %q to %q", invalid.name, invalid.value)
+		}
+	}
+
+	if names := s.AliasNames(); !reflect.DeepEqual(names, []string{"dep", "ll"}) {
+		t.Errorf("expected sorted alias names but got %q", names)
+	}
+
+	// Only the user defined alias should be persisted
+	loaded := New()
+	if err := loaded.LoadAliases(file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if names := loaded.AliasNames(); !reflect.DeepEqual(names, []string{"ll"}) {
+		t.Errorf("expected only the user alias to be persisted but got %q", names)
+	}
+
+	if found, err := loaded.RemoveAlias("ll"); !found || err != nil {
+		t.Errorf("expected to remove the alias, got found=%v err=%v", found, err)
+	}
+	reloaded := New()
+	if err := reloaded.LoadAliases(file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, found := reloaded.Alias("ll"); found {
+		t.Errorf("expected the removed alias to not be persisted")
+	}
+}
+
+func TestSessionJobs(t *testing.T) {
+	s := New()
+	if _, err := s.FindJob(""); err == nil {
+		t.Errorf("expected an error finding the current job with no jobs")
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	first := s.StartJob("tail -f app.log", cancel1)
+	_, cancel2 := context.WithCancel(context.Background())
+	second := s.StartJob("build", cancel2)
+
+	if first.ID != 1 || second.ID != 2 {
+		t.Errorf("expected job numbers 1 and 2 but got %d and %d", first.ID, second.ID)
+	}
+
+	for spec, expected := range map[string]*Job{"": second, "%%": second, "%+": second, "%1": first, "1": first, "%2": second} {
+		if job, err := s.FindJob(spec); err != nil || job != expected {
+			t.Errorf("FindJob(%q): expected job %d but got %v (err %v)", spec, expected.ID, job, err)
+		}
+	}
+	if _, err := s.FindJob("%3"); err == nil {
+		t.Errorf("expected an error finding a missing job")
+	}
+
+	if err := s.KillJob(first); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ctx1.Err() == nil {
+		t.Errorf("expected killing the job to cancel its context")
+	}
+	s.FinishJob(first, ctx1.Err())
+	s.FinishJob(second, errors.New("failed"))
+
+	select {
+	case <-first.Done():
+	default:
+		t.Errorf("expected the job to be done once finished")
+	}
+
+	if state, _ := s.JobState(first); state != JobKilled {
+		t.Errorf("expected the first job to be killed but got %s", state)
+	}
+	if state, err := s.JobState(second); state != JobFailed || err == nil {
+		t.Errorf("expected the second job to have failed but got %s (err %v)", state, err)
+	}
+	if err := s.KillJob(second); err == nil {
+		t.Errorf("expected an error killing a finished job")
+	}
+
+	s.RemoveJob(first)
+	s.RemoveJob(second)
+	if len(s.Jobs()) != 0 {
+		t.Errorf("expected no jobs once removed")
+	}
+	if job := s.StartJob("deploy", func() {}); job.ID != 1 {
+		t.Errorf("expected job numbers to be reused but got %d", job.ID)
+	}
+}
@@ -0,0 +1,156 @@
+// Package stdin provides the input for commands run by the interactive shell,
+// which is fed with the lines the user types while the command is running.
+package stdin
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Input is an [io.Reader] which returns the lines the user has typed into
+// the shell while a command is running.
+//
+// Reads block until the user enters a line, the user ends the input, or
+// the context of the command is done.
+type Input struct {
+	ctx context.Context
+
+	mu      sync.Mutex
+	buf     []byte
+	eof     bool
+	used    bool // set once the command has read from the input
+	masked  bool
+	echo    io.Writer
+	changed chan struct{} // closed and replaced when the input changes
+}
+
+var _ io.Reader = (*Input)(nil)
+
+// New creates a new input for the command running with the given context
+func New(ctx context.Context) *Input {
+	return &Input{
+		ctx:     ctx,
+		changed: make(chan struct{}),
+	}
+}
+
+// Read implements [io.Reader]
+func (i *Input) Read(p []byte) (n int, err error) {
+	for {
+		i.mu.Lock()
+		i.used = true
+		switch {
+		case len(i.buf) > 0:
+			n = copy(p, i.buf)
+			i.buf = i.buf[n:]
+			i.mu.Unlock()
+			return n, nil
+
+		case i.eof:
+			i.mu.Unlock()
+			return 0, io.EOF
+		}
+		changed := i.changed
+		i.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-i.ctx.Done():
+			return 0, errors.WithStack(i.ctx.Err())
+		}
+	}
+}
+
+// WriteLine adds the line the user has entered to the input, and
+// echoes it to the output of the command
+//
+// If the input is masked, the line is not echoed.
+func (i *Input) WriteLine(line string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	if i.eof {
+		return
+	}
+
+	i.buf = append(i.buf, line+"\n"...)
+	if i.echo != nil {
+		if i.masked {
+			_, _ = io.WriteString(i.echo, "\n")
+		} else {
+			_, _ = io.WriteString(i.echo, line+"\n")
+		}
+	}
+	i.notify()
+}
+
+// CloseWrite ends the input, so once the lines already entered
+// have been read, reads return [io.EOF]
+func (i *Input) CloseWrite() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.eof = true
+	i.notify()
+}
+
+// SetMasked sets whether the input is masked, which is used
+// when the command is reading sensitive input such as passwords
+func (i *Input) SetMasked(masked bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.masked = masked
+}
+
+// Masked reports whether the input is masked
+func (i *Input) Masked() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.masked
+}
+
+// Used reports whether the command has read from the input, so
+// is expecting the user to type into it
+func (i *Input) Used() bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	return i.used
+}
+
+// SetEcho sets the writer lines entered by the user are echoed to,
+// or stops echoing lines if w is nil
+func (i *Input) SetEcho(w io.Writer) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.echo = w
+}
+
+// notify wakes up any blocked reads, and must be called under lock
+func (i *Input) notify() {
+	close(i.changed)
+	i.changed = make(chan struct{})
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying the input
+func NewContext(ctx context.Context, input *Input) context.Context {
+	return context.WithValue(ctx, contextKey{}, input)
+}
+
+// FromContext returns the input from the context, or nil if there is none
+func FromContext(ctx context.Context) *Input {
+	if ctx == nil {
+		return nil
+	}
+
+	input, _ := ctx.Value(contextKey{}).(*Input)
+	return input
+}
@@ -0,0 +1,90 @@
+package stdin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestInputReadsLines(t *testing.T) {
+	input := New(context.Background())
+	if input.Used() {
+		t.Errorf("expected the input not to be used before it was read")
+	}
+
+	var echo bytes.Buffer
+	input.SetEcho(&echo)
+
+	lines := make(chan string)
+	go func() {
+		reader := bufio.NewReader(input)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			lines <- line
+		}
+	}()
+
+	input.WriteLine("first")
+	if line := <-lines; line != "first\n" {
+		t.Errorf("expected %q but got %q", "first\n", line)
+	}
+	if !input.Used() {
+		t.Errorf("expected the input to be used once it had been read")
+	}
+
+	input.SetMasked(true)
+	input.WriteLine("secret")
+	if line := <-lines; line != "secret\n" {
+		t.Errorf("expected %q but got %q", "secret\n", line)
+	}
+
+	input.CloseWrite()
+	if _, open := <-lines; open {
+		t.Errorf("expected the input to be at EOF")
+	}
+
+	if expected := "first\n\n"; echo.String() != expected {
+		t.Errorf("expected echo %q but got %q", expected, echo.String())
+	}
+}
+
+func TestInputReadReturnsWhenContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	input := New(ctx)
+
+	errs := make(chan error)
+	go func() {
+		_, err := io.ReadAll(input)
+		errs <- err
+	}()
+
+	cancel()
+	select {
+	case err := <-errs:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("expected context.Canceled but got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected the read to return once the context was cancelled")
+	}
+}
+
+func TestInputContext(t *testing.T) {
+	if FromContext(context.Background()) != nil {
+		t.Errorf("expected no input from an empty context")
+	}
+
+	input := New(context.Background())
+	if got := FromContext(NewContext(context.Background(), input)); got != input {
+		t.Errorf("expected the input from the context")
+	}
+}
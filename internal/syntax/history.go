@@ -0,0 +1,187 @@
+package syntax
+
+import (
+	stderrors "errors"
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+var (
+	// ErrEventNotFound is returned when a history expansion refers to a command which isn't in the history
+	ErrEventNotFound = stderrors.New("event not found")
+
+	// ErrSubstitutionFailed is returned when the text to replace in a quick substitution isn't in the last command
+	ErrSubstitutionFailed = stderrors.New("substitution failed")
+)
+
+// historyDelimiters are the characters which end a reference to a command by its prefix, such as `!dep`
+const historyDelimiters = " \t\n;&|<>()'\"`"
+
+// ExpandHistory expands the bash style references to previously run commands within the line,
+// given the lines of those commands, oldest first:
+//
+//   - `!!` is the last command
+//   - `!n` is command number n, counting from 1 for the oldest command
+//   - `!-n` is the command n commands ago, so `!-1` is the same as `!!`
+//   - `!prefix` is the most recent command starting with prefix
+//   - `!$` is the last word of the last command
+//   - `^old^new` at the start of the line is the last command with the first `old` replaced
+//     by `new`, with anything after a closing `^` added to the end
+//
+// References are not expanded within single quotes, after a backslash, or when the `!` is
+// followed by whitespace, `=`, `(` or the end of the line. expanded is true if the line
+// contained any references.
+func ExpandHistory(line string, commands []string) (result string, expanded bool, err error) {
+	if strings.HasPrefix(line, "^") {
+		result, err := quickSubstitution(line, commands)
+		return result, err == nil, err
+	}
+
+	var (
+		sb       strings.Builder
+		inSingle bool
+		inDouble bool
+	)
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == '\\' && !inSingle && i+1 < len(line):
+			sb.WriteString(line[i : i+2])
+			i++
+			continue
+
+		case c == '\'' && !inDouble:
+			inSingle = !inSingle
+
+		case c == '"' && !inSingle:
+			inDouble = !inDouble
+
+		case c == '!' && !inSingle:
+			if ref := historyReference(line[i+1:]); ref != "" {
+				text, err := resolveHistoryReference(ref, commands)
+				if err != nil {
+					return line, false, errors.Wrapf(err, "!%s", ref)
+				}
+
+				sb.WriteString(text)
+				i += len(ref)
+				expanded = true
+				continue
+			}
+		}
+
+		sb.WriteByte(c)
+	}
+
+	return sb.String(), expanded, nil
+}
+
+// historyReference returns the reference to a command at the start of rest,
+// which follows a `!`, or an empty string if the `!` is not a reference
+func historyReference(rest string) string {
+	if rest == "" {
+		return ""
+	}
+
+	switch c := rest[0]; {
+	case c == '!' || c == '$':
+		return rest[:1]
+
+	case c == '-' || isDigit(c):
+		end := 1
+		for end < len(rest) && isDigit(rest[end]) {
+			end++
+		}
+		if c == '-' && end == 1 {
+			return ""
+		}
+		return rest[:end]
+
+	case c == '=' || strings.IndexByte(historyDelimiters, c) >= 0:
+		return ""
+
+	default:
+		end := strings.IndexAny(rest, historyDelimiters)
+		if end < 0 {
+			end = len(rest)
+		}
+		return rest[:end]
+	}
+}
+
+// resolveHistoryReference returns the text the reference expands to
+func resolveHistoryReference(ref string, commands []string) (string, error) {
+	switch {
+	case ref == "!":
+		return nthCommand(len(commands), commands)
+
+	case ref == "$":
+		last, err := nthCommand(len(commands), commands)
+		if err != nil {
+			return "", err
+		}
+		return lastWord(last)
+
+	case ref[0] == '-':
+		n, _ := strconv.Atoi(ref[1:])
+		return nthCommand(len(commands)-n+1, commands)
+
+	case isDigit(ref[0]):
+		n, _ := strconv.Atoi(ref)
+		return nthCommand(n, commands)
+
+	default:
+		for i := len(commands) - 1; i >= 0; i-- {
+			if strings.HasPrefix(commands[i], ref) {
+				return commands[i], nil
+			}
+		}
+		return "", errors.WithStack(ErrEventNotFound)
+	}
+}
+
+// nthCommand returns command n, counting from 1 for the oldest command
+func nthCommand(n int, commands []string) (string, error) {
+	if n < 1 || n > len(commands) {
+		return "", errors.WithStack(ErrEventNotFound)
+	}
+	return commands[n-1], nil
+}
+
+// lastWord returns the last word of the line as it was typed, including any quoting
+func lastWord(line string) (string, error) {
+	tokens, _ := Lex(line)
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Kind == WordToken {
+			return line[tokens[i].Start:tokens[i].End], nil
+		}
+	}
+	return "", errors.WithStack(ErrEventNotFound)
+}
+
+// quickSubstitution expands a line of the form `^old^new^rest`
+func quickSubstitution(line string, commands []string) (string, error) {
+	parts := strings.SplitN(line[1:], "^", 3)
+	old := parts[0]
+
+	var replacement, rest string
+	if len(parts) > 1 {
+		replacement = parts[1]
+	}
+	if len(parts) > 2 {
+		rest = parts[2]
+	}
+
+	last, err := nthCommand(len(commands), commands)
+	if err != nil {
+		return line, errors.Wrap(err, "^")
+	}
+	if old == "" || !strings.Contains(last, old) {
+		return line, errors.WithStack(ErrSubstitutionFailed)
+	}
+
+	return strings.Replace(last, old, replacement, 1) + rest, nil
+}
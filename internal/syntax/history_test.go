@@ -0,0 +1,81 @@
+package syntax
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestExpandHistory(t *testing.T) {
+	commands := []string{"deploy api --env staging", "status", `note add "hello world"`}
+
+	tests := []struct {
+		line     string
+		expected string
+		err      error
+	}{
+		{line: "echo hi", expected: "echo hi"},
+		{line: "!!", expected: `note add "hello world"`},
+		{line: "sudo !! && echo done", expected: `sudo note add "hello world" && echo done`},
+		{line: "!1", expected: "deploy api --env staging"},
+		{line: "!-2", expected: "status"},
+		{line: "!dep --dry-run", expected: "deploy api --env staging --dry-run"},
+		{line: "!st|grep ok", expected: "status|grep ok"},
+		{line: "echo !$", expected: `echo "hello world"`},
+		{line: "echo '!!' \\!! \"!!\"", expected: `echo '!!' \!! "note add "hello world""`},
+		{line: "echo hi! a != b !(x) !", expected: "echo hi! a != b !(x) !"},
+		{line: "echo !-", expected: "echo !-"},
+		{line: "!4", err: ErrEventNotFound},
+		{line: "!0", err: ErrEventNotFound},
+		{line: "!-4", err: ErrEventNotFound},
+		{line: "!missing", err: ErrEventNotFound},
+	}
+
+	for _, test := range tests {
+		got, expanded, err := ExpandHistory(test.line, commands)
+		if !errors.Is(err, test.err) {
+			t.Errorf("ExpandHistory(%q): expected error %v but got %v", test.line, test.err, err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+		if got != test.expected {
+			t.Errorf("ExpandHistory(%q): expected %q but got %q", test.line, test.expected, got)
+		}
+		if expanded != (got != test.line) {
+			t.Errorf("ExpandHistory(%q): expected expanded to be %v but got %v", test.line, got != test.line, expanded)
+		}
+	}
+}
+
+func TestQuickSubstitution(t *testing.T) {
+	commands := []string{"deploy api --env staging", "deploy web --env staging"}
+
+	tests := []struct {
+		line     string
+		expected string
+		err      error
+	}{
+		{line: "^staging^prod", expected: "deploy web --env prod"},
+		{line: "^web^api^ --dry-run", expected: "deploy api --env staging --dry-run"},
+		{line: "^ --env staging", expected: "deploy web"},
+		{line: "^missing^x", err: ErrSubstitutionFailed},
+		{line: "^^x", err: ErrSubstitutionFailed},
+	}
+
+	for _, test := range tests {
+		got, expanded, err := ExpandHistory(test.line, commands)
+		if !errors.Is(err, test.err) {
+			t.Errorf("ExpandHistory(%q): expected error %v but got %v", test.line, test.err, err)
+			continue
+		}
+		if test.err == nil && (got != test.expected || !expanded) {
+			t.Errorf("ExpandHistory(%q): expected %q but got %q (expanded %v)", test.line, test.expected, got, expanded)
+		}
+	}
+
+	if _, _, err := ExpandHistory("^a^b", nil); !errors.Is(err, ErrEventNotFound) {
+		t.Errorf("expected a quick substitution with no history to fail, but got %v", err)
+	}
+}
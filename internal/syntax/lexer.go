@@ -0,0 +1,308 @@
+// Package syntax implements the parsing of the lines users type into the shell,
+// following the POSIX shell rules for quoting and escaping.
+package syntax
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// Sentinel errors are created without a stack trace, as they are wrapped
+// with the stack of where they are returned from
+var (
+	// ErrUnterminatedQuote is returned when a line ends while inside a quoted string
+	ErrUnterminatedQuote = stderrors.New("unterminated quoted string")
+
+	// ErrTrailingEscape is returned when a line ends with an unescaped backslash
+	ErrTrailingEscape = stderrors.New("line ends with an escape character")
+)
+
+// TokenKind is the type of token returned by [Lex]
+type TokenKind uint8
+
+const (
+	WordToken     TokenKind = iota // A word, such as a command name or argument
+	OperatorToken                  // A control operator, such as |, ;, &, && or ||
+	RedirectToken                  // A redirection operator, such as > or 2>>
+)
+
+// Token is a single word or operator from a line
+type Token struct {
+	Kind  TokenKind // The type of the token
+	Value string    // The value of the token with all quoting and escaping removed
+	Word  Word      // The parts of a word token, used to expand any variables within it
+	Start int       // The byte offset in the line where the token starts
+	End   int       // The byte offset in the line where the token ends (exclusive)
+}
+
+// Lex splits the line into words and operators using the POSIX shell rules:
+//
+//   - Unquoted whitespace separates words, with runs of whitespace collapsing
+//   - An unquoted operator (such as | or >) separates words and is returned as its own token
+//   - A redirection operator may be prefixed by a file descriptor number (such as 2>)
+//   - A backslash outside of quotes preserves the literal value of the next character
+//   - Single quotes preserve the literal value of every character within them
+//   - Double quotes preserve the literal value of every character within them, except
+//     for a backslash which escapes a following $, `, ", \ or newline
+//   - Outside of single quotes, `$name`, `${name}` and `$?` are references to variables,
+//     which are recorded in the [Token.Word] and left unexpanded in the [Token.Value]
+//   - An unquoted # at the start of a word begins a comment, which continues to the end of the line
+//
+// If the line is incomplete (i.e. it ends inside a quote) then the tokens lexed so far
+// are returned, including the partial final word, alongside an error.
+func Lex(line string) ([]Token, error) {
+	var (
+		tokens  []Token
+		current wordBuilder
+		inWord  bool
+		start   int
+	)
+
+	endWord := func(end int) {
+		if inWord {
+			word := current.word()
+			tokens = append(tokens, Token{Value: word.String(), Word: word, Start: start, End: end})
+			inWord = false
+		}
+	}
+	startWord := func(pos int) {
+		if !inWord {
+			inWord = true
+			start = pos
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch c {
+		case ' ', '\t', '\n':
+			endWord(i)
+
+		case '|', ';':
+			endWord(i)
+
+			opStart := i
+			if c == '|' && i+1 < len(line) && line[i+1] == '|' {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: OperatorToken, Value: line[opStart : i+1], Start: opStart, End: i + 1})
+
+		case '&':
+			endWord(i)
+
+			opStart := i
+			if i+1 < len(line) && line[i+1] == '&' {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: OperatorToken, Value: line[opStart : i+1], Start: opStart, End: i + 1})
+
+		case '<', '>':
+			opStart := i
+
+			// If the current word is an unquoted number, it's the file descriptor for the redirect
+			if inWord && isDigits(line[start:i]) {
+				opStart = start
+				current.word()
+				inWord = false
+			}
+			endWord(i)
+
+			if c == '>' && i+1 < len(line) && line[i+1] == '>' {
+				i++
+			}
+			tokens = append(tokens, Token{Kind: RedirectToken, Value: line[opStart : i+1], Start: opStart, End: i + 1})
+
+		case '\\':
+			if i+1 >= len(line) {
+				startWord(i)
+				endWord(len(line))
+				return tokens, errors.WithStack(ErrTrailingEscape)
+			}
+
+			i++
+			if line[i] == '\n' {
+				// A backslash-newline is a line continuation and is removed entirely
+				continue
+			}
+
+			startWord(i - 1)
+			current.writeByte(line[i])
+
+		case '#':
+			if inWord {
+				current.writeByte(c)
+				continue
+			}
+
+			// Skip to the end of the line, leaving the newline to be handled as whitespace
+			if end := strings.IndexByte(line[i:], '\n'); end != -1 {
+				i += end - 1
+			} else {
+				i = len(line)
+			}
+
+		case '\'':
+			startWord(i)
+
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end == -1 {
+				current.writeString(line[i+1:])
+				endWord(len(line))
+				return tokens, errors.WithStack(ErrUnterminatedQuote)
+			}
+
+			current.writeString(line[i+1 : i+1+end])
+			i += end + 1
+
+		case '"':
+			startWord(i)
+
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("$`\"\\\n", line[i+1]) != -1 {
+					i++
+					if line[i] == '\n' {
+						continue
+					}
+				} else if line[i] == '$' {
+					if name, end, ok := readVariable(line, i); ok {
+						current.writeVariable(name)
+						i = end
+						continue
+					}
+				}
+
+				current.writeByte(line[i])
+			}
+
+			if !closed {
+				endWord(len(line))
+				return tokens, errors.WithStack(ErrUnterminatedQuote)
+			}
+
+		case '$':
+			startWord(i)
+
+			if name, end, ok := readVariable(line, i); ok {
+				current.writeVariable(name)
+				i = end
+			} else {
+				current.writeByte(c)
+			}
+
+		default:
+			startWord(i)
+			current.writeByte(c)
+		}
+	}
+
+	endWord(len(line))
+	return tokens, nil
+}
+
+// isDigits returns true if str is non-empty and only contains the digits 0-9
+func isDigits(str string) bool {
+	if str == "" {
+		return false
+	}
+
+	for i := 0; i < len(str); i++ {
+		if str[i] < '0' || str[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Split splits the line into words following the same rules as [Lex]
+//
+// An error is returned if the line contains any operators.
+func Split(line string) ([]string, error) {
+	tokens, err := Lex(line)
+	if err != nil {
+		return nil, err
+	}
+
+	words := make([]string, len(tokens))
+	for i, token := range tokens {
+		if token.Kind != WordToken {
+			return nil, errors.Newf("unexpected operator `%s`", token.Value)
+		}
+		words[i] = token.Value
+	}
+	return words, nil
+}
+
+// CompletionArgs lexes a partially typed line for tab completion.
+//
+// It returns the words of the last command on the line, where the final word
+// is the one being completed (which will be empty if the user is starting a
+// new word), along with the byte offset in the line where that final word starts.
+//
+// Redirections are not passed to commands, so they are not included in the
+// returned words. If the word being completed is the target of a redirection
+// then no words are returned.
+func CompletionArgs(line string) (args []string, wordStart int) {
+	// Errors are ignored as we expect partial lines here, and Lex
+	// still returns the partial word
+	tokens, _ := Lex(line)
+
+	// Only the command after the last operator is being completed
+	for i := len(tokens) - 1; i >= 0; i-- {
+		if tokens[i].Kind == OperatorToken {
+			tokens = tokens[i+1:]
+			break
+		}
+	}
+
+	newWord := len(tokens) == 0 || tokens[len(tokens)-1].Kind != WordToken || tokens[len(tokens)-1].End < len(line)
+	wordStart = len(line)
+	if !newWord {
+		wordStart = tokens[len(tokens)-1].Start
+	}
+
+	args = make([]string, 0, len(tokens)+1)
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i].Kind == RedirectToken {
+			// Skip the target of the redirect
+			i++
+
+			if i >= len(tokens) || (i == len(tokens)-1 && !newWord) {
+				// We're completing the target of the redirect
+				return nil, wordStart
+			}
+			continue
+		}
+
+		args = append(args, tokens[i].Value)
+	}
+
+	if newWord {
+		args = append(args, "")
+	}
+
+	return args, wordStart
+}
+
+// Quote returns the value quoted such that [Lex] would return it as a single word
+//
+// If the value does not need quoting it is returned as is.
+func Quote(value string) string {
+	if value == "" {
+		return "''"
+	}
+
+	if !strings.ContainsAny(value, " \t\n\\'\"$`!#&*;<>?()[]{}|~") {
+		return value
+	}
+
+	return "'" + strings.ReplaceAll(value, "'", `'\''`) + "'"
+}
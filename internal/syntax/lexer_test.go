@@ -0,0 +1,115 @@
+package syntax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected []string
+		err      error
+	}{
+		{line: "", expected: []string{}},
+		{line: "note add hello", expected: []string{"note", "add", "hello"}},
+		{line: "  note   add \t hello  ", expected: []string{"note", "add", "hello"}},
+		{line: `note add "hello world"`, expected: []string{"note", "add", "hello world"}},
+		{line: `note add 'hello "world"'`, expected: []string{"note", "add", `hello "world"`}},
+		{line: `note add "it's \"here\" \$5 \n"`, expected: []string{"note", "add", `it's "here" $5 \n`}},
+		{line: `note add hello\ world`, expected: []string{"note", "add", "hello world"}},
+		{line: `a"b c"d'e f'`, expected: []string{"ab cde f"}},
+		{line: `echo "" ''`, expected: []string{"echo", "", ""}},
+		{line: "echo a\\\nb", expected: []string{"echo", "ab"}},
+		{line: "echo a#b # comment \"unterminated", expected: []string{"echo", "a#b"}},
+		{line: "# just a comment", expected: []string{}},
+		{line: "echo a # comment\necho b", expected: []string{"echo", "a", "echo", "b"}},
+		{line: `echo "hello`, err: ErrUnterminatedQuote},
+		{line: `echo 'hello`, err: ErrUnterminatedQuote},
+		{line: `echo hello\`, err: ErrTrailingEscape},
+	}
+
+	for _, test := range tests {
+		got, err := Split(test.line)
+		if !errors.Is(err, test.err) {
+			t.Errorf("Split(%q): expected error %v but got %v", test.line, test.err, err)
+			continue
+		}
+
+		if test.err == nil && !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Split(%q): expected %q but got %q", test.line, test.expected, got)
+		}
+	}
+}
+
+func TestCompletionArgs(t *testing.T) {
+	tests := []struct {
+		line      string
+		args      []string
+		wordStart int
+	}{
+		{line: "", args: []string{""}, wordStart: 0},
+		{line: "no", args: []string{"no"}, wordStart: 0},
+		{line: "note ", args: []string{"note", ""}, wordStart: 5},
+		{line: "note  add", args: []string{"note", "add"}, wordStart: 6},
+		{line: `note add "hello wo`, args: []string{"note", "add", "hello wo"}, wordStart: 9},
+		{line: `note add hello\ `, args: []string{"note", "add", "hello "}, wordStart: 9},
+		{line: "list | fi", args: []string{"fi"}, wordStart: 7},
+		{line: "list |", args: []string{""}, wordStart: 6},
+		{line: "tail & li", args: []string{"li"}, wordStart: 7},
+		{line: "report > out.txt --a", args: []string{"report", "--a"}, wordStart: 17},
+		{line: "report > out", args: nil, wordStart: 9},
+		{line: "report >", args: nil, wordStart: 8},
+	}
+
+	for _, test := range tests {
+		args, wordStart := CompletionArgs(test.line)
+		if !reflect.DeepEqual(args, test.args) {
+			t.Errorf("CompletionArgs(%q): expected args %q but got %q", test.line, test.args, args)
+		}
+		if wordStart != test.wordStart {
+			t.Errorf("CompletionArgs(%q): expected word start %d but got %d", test.line, test.wordStart, wordStart)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	for _, value := range []string{"", "simple", "hello world", `it's`, `"quoted"`, `$HOME`, `back\slash`} {
+		got, err := Split(Quote(value))
+		if err != nil {
+			t.Errorf("Quote(%q) produced %q which could not be split: %v", value, Quote(value), err)
+			continue
+		}
+
+		if len(got) != 1 || got[0] != value {
+			t.Errorf("Quote(%q) produced %q which split to %q", value, Quote(value), got)
+		}
+	}
+}
+
+func TestLexVariables(t *testing.T) {
+	vars := map[string]string{"name": "bob", "?": "0", "spaced": "a b"}
+	lookup := func(name string) string { return vars[name] }
+
+	tokens, err := Lex(`echo $name "${name}s" '$name' \$name $? $spaced $ "$" ${missing}x $9`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{"echo", "bob", "bobs", "$name", "$name", "0", "a b", "$", "$", "x", "$9"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("expected %d tokens but got %d", len(expected), len(tokens))
+	}
+
+	for i, token := range tokens {
+		if got := token.Word.Expand(lookup); got != expected[i] {
+			t.Errorf("token %d (%s): expected %q but got %q", i, token.Value, expected[i], got)
+		}
+	}
+
+	if tokens[2].Value != "${name}s" {
+		t.Errorf("expected the value of a word to keep the variable reference, got %q", tokens[2].Value)
+	}
+}
@@ -0,0 +1,346 @@
+package syntax
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/cockroachdb/errors"
+)
+
+// List is a sequence of [AndOr] lists separated by `;` or `&`, which are run one after another
+type List struct {
+	Items []AndOr
+}
+
+// Pipelines returns all the pipelines in the list in the order they appear
+func (l List) Pipelines() []Pipeline {
+	var pipelines []Pipeline
+	for _, item := range l.Items {
+		pipelines = append(pipelines, item.Pipelines...)
+	}
+	return pipelines
+}
+
+// AndOr is a sequence of pipelines joined by `&&` or `||`, where each pipeline
+// is run depending on the result of the previously run pipeline
+type AndOr struct {
+	Background bool   // If the list was terminated by `&` and should be run as a background job
+	Source     string // The text from the line this list was parsed from, excluding the terminator
+	Pipelines  []Pipeline
+}
+
+// Condition is the condition under which a pipeline is run
+type Condition uint8
+
+const (
+	Always    Condition = iota // The pipeline is always run
+	OnSuccess                  // The pipeline is only run if the previous pipeline succeeded (&&)
+	OnFailure                  // The pipeline is only run if the previous pipeline failed (||)
+)
+
+// ShouldRun returns true if a pipeline with this condition should
+// be run given the result of the previously run pipeline
+func (c Condition) ShouldRun(previousSucceeded bool) bool {
+	switch c {
+	case OnSuccess:
+		return previousSucceeded
+	case OnFailure:
+		return !previousSucceeded
+	default:
+		return true
+	}
+}
+
+// Pipeline is a sequence of commands where the output of each
+// command is fed into the input of the next command
+type Pipeline struct {
+	Condition Condition // The condition under which this pipeline is run
+	Source    string    // The text from the line this pipeline was parsed from
+	Commands  []Command
+}
+
+// Command is a single command to be executed
+type Command struct {
+	Args      []Word     // The command name followed by its arguments
+	Redirects []Redirect // Any redirections of the commands input or output
+}
+
+// ExpandArgs returns the arguments of the command with all variables expanded
+func (c Command) ExpandArgs(lookup func(name string) string) []string {
+	args := make([]string, len(c.Args))
+	for i, arg := range c.Args {
+		args[i] = arg.Expand(lookup)
+	}
+	return args
+}
+
+// ExpandAliases replaces the command name with the words of its alias, if it has one,
+// with the rest of the arguments following on from the alias.
+//
+// An alias may start with the name of another alias, which will also be expanded,
+// however an alias is never expanded within itself so `alias ls='ls -l'` is valid.
+func (c Command) ExpandAliases(lookup func(name string) (string, bool)) (Command, error) {
+	seen := make(map[string]bool)
+
+	for len(c.Args) > 0 && !c.Args[0].HasVariables() {
+		name := c.Args[0].String()
+		if seen[name] {
+			break
+		}
+
+		value, found := lookup(name)
+		if !found {
+			break
+		}
+		seen[name] = true
+
+		tokens, err := Lex(value)
+		if err != nil {
+			return Command{}, errors.Wrapf(err, "unable to expand alias %q", name)
+		}
+
+		args := make([]Word, 0, len(tokens)+len(c.Args)-1)
+		for _, token := range tokens {
+			if token.Kind != WordToken {
+				return Command{}, errors.Newf("unable to expand alias %q: unexpected operator `%s`", name, token.Value)
+			}
+			args = append(args, token.Word)
+		}
+		if len(args) == 0 {
+			return Command{}, errors.Newf("unable to expand alias %q: alias has no value", name)
+		}
+
+		c.Args = append(args, c.Args[1:]...)
+	}
+
+	return c, nil
+}
+
+// RedirectKind is the type of redirection
+type RedirectKind uint8
+
+const (
+	RedirectInput  RedirectKind = iota // Read the file as input (<)
+	RedirectOutput                     // Write the output to the file, truncating it (>)
+	RedirectAppend                     // Append the output to the file (>>)
+)
+
+// Standard file descriptors which can be redirected
+const (
+	Stdin  = 0
+	Stdout = 1
+	Stderr = 2
+)
+
+// Redirect is a redirection of one of a command's streams to or from a file
+type Redirect struct {
+	Kind   RedirectKind // The type of redirection
+	Fd     int          // The file descriptor being redirected
+	Target Word         // The file being redirected to or from
+}
+
+// Parse parses the line into a [List]
+//
+// If the line is empty then an empty list is returned
+func Parse(line string) (List, error) {
+	tokens, err := Lex(line)
+	if err != nil {
+		return List{}, err
+	}
+
+	p := &parser{line: line, tokens: tokens}
+	return p.parseList()
+}
+
+// parser is a recursive descent parser over the tokens of a line
+type parser struct {
+	line   string
+	tokens []Token
+	pos    int
+}
+
+// peek returns the next token without consuming it
+func (p *parser) peek() (Token, bool) {
+	if p.pos >= len(p.tokens) {
+		return Token{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+// peekOperator returns true if the next token is the given operator
+func (p *parser) peekOperator(operator string) bool {
+	token, ok := p.peek()
+	return ok && token.Kind == OperatorToken && token.Value == operator
+}
+
+func (p *parser) parseList() (List, error) {
+	var list List
+
+	for {
+		if _, ok := p.peek(); !ok {
+			return list, nil
+		}
+
+		andOr, err := p.parseAndOr()
+		if err != nil {
+			return List{}, err
+		}
+
+		switch {
+		case p.peekOperator("&"):
+			andOr.Background = true
+		case !p.peekOperator(";"):
+			if token, ok := p.peek(); ok {
+				return List{}, unexpectedToken(token)
+			}
+			list.Items = append(list.Items, andOr)
+			return list, nil
+		}
+
+		list.Items = append(list.Items, andOr)
+		p.pos++
+	}
+}
+
+func (p *parser) parseAndOr() (AndOr, error) {
+	var andOr AndOr
+
+	start := p.tokens[p.pos]
+
+	condition := Always
+	for {
+		pipeline, err := p.parsePipeline()
+		if err != nil {
+			return AndOr{}, err
+		}
+		pipeline.Condition = condition
+		andOr.Pipelines = append(andOr.Pipelines, pipeline)
+
+		switch {
+		case p.peekOperator("&&"):
+			condition = OnSuccess
+		case p.peekOperator("||"):
+			condition = OnFailure
+		default:
+			andOr.Source = p.line[start.Start:p.tokens[p.pos-1].End]
+			return andOr, nil
+		}
+		p.pos++
+	}
+}
+
+func (p *parser) parsePipeline() (Pipeline, error) {
+	var pipeline Pipeline
+
+	start, ok := p.peek()
+	if !ok {
+		return Pipeline{}, unexpectedEndOfLine()
+	}
+
+	for {
+		cmd, err := p.parseCommand()
+		if err != nil {
+			return Pipeline{}, err
+		}
+		pipeline.Commands = append(pipeline.Commands, cmd)
+
+		if !p.peekOperator("|") {
+			break
+		}
+		p.pos++
+	}
+
+	pipeline.Source = p.line[start.Start:p.tokens[p.pos-1].End]
+	return pipeline, nil
+}
+
+func (p *parser) parseCommand() (Command, error) {
+	var cmd Command
+
+loop:
+	for {
+		token, ok := p.peek()
+		if !ok {
+			break
+		}
+
+		switch token.Kind {
+		case WordToken:
+			cmd.Args = append(cmd.Args, token.Word)
+			p.pos++
+
+		case RedirectToken:
+			p.pos++
+			target, ok := p.peek()
+			if ok && target.Kind == OperatorToken && target.Value == "&" && target.Start == token.End {
+				return Command{}, errors.Newf("unsupported redirection `%s&`: file descriptor duplication is not supported", token.Value)
+			}
+			if !ok || target.Kind != WordToken {
+				return Command{}, errors.Newf("syntax error: missing file name after `%s`", token.Value)
+			}
+
+			redirect, err := parseRedirect(token.Value)
+			if err != nil {
+				return Command{}, err
+			}
+
+			redirect.Target = target.Word
+			cmd.Redirects = append(cmd.Redirects, redirect)
+			p.pos++
+
+		default:
+			break loop
+		}
+	}
+
+	if len(cmd.Args) == 0 {
+		if token, ok := p.peek(); ok {
+			return Command{}, unexpectedToken(token)
+		}
+		return Command{}, unexpectedEndOfLine()
+	}
+
+	return cmd, nil
+}
+
+// parseRedirect parses a redirection operator, such as `2>>`
+func parseRedirect(operator string) (Redirect, error) {
+	op := strings.TrimLeft(operator, "0123456789")
+
+	var redirect Redirect
+	switch op {
+	case "<":
+		redirect = Redirect{Kind: RedirectInput, Fd: Stdin}
+	case ">":
+		redirect = Redirect{Kind: RedirectOutput, Fd: Stdout}
+	case ">>":
+		redirect = Redirect{Kind: RedirectAppend, Fd: Stdout}
+	default:
+		return Redirect{}, errors.Newf("syntax error: unknown redirection `%s`", operator)
+	}
+
+	if fdStr := strings.TrimSuffix(operator, op); fdStr != "" {
+		fd, err := strconv.Atoi(fdStr)
+		if err != nil {
+			return Redirect{}, errors.Wrapf(err, "syntax error: invalid file descriptor in `%s`", operator)
+		}
+		redirect.Fd = fd
+	}
+
+	switch {
+	case redirect.Kind == RedirectInput && redirect.Fd != Stdin:
+		return Redirect{}, errors.Newf("unsupported redirection `%s`: only stdin can be read from a file", operator)
+	case redirect.Kind != RedirectInput && redirect.Fd != Stdout && redirect.Fd != Stderr:
+		return Redirect{}, errors.Newf("unsupported redirection `%s`: only stdout and stderr can be written to a file", operator)
+	}
+
+	return redirect, nil
+}
+
+func unexpectedEndOfLine() error {
+	return errors.New("syntax error: unexpected end of line")
+}
+
+func unexpectedToken(token Token) error {
+	return errors.Newf("syntax error near unexpected token `%s`", token.Value)
+}
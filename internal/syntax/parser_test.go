@@ -0,0 +1,202 @@
+package syntax
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected List
+		err      bool
+	}{
+		{line: "", expected: List{}},
+		{
+			line: "list-users",
+			expected: List{Items: []AndOr{{Source: "list-users", Pipelines: []Pipeline{
+				{Source: "list-users", Commands: []Command{{Args: words("list-users")}}},
+			}}}},
+		},
+		{
+			line: `list-users | filter --name "a | b"|count`,
+			expected: List{Items: []AndOr{{Source: `list-users | filter --name "a | b"|count`, Pipelines: []Pipeline{
+				{Source: `list-users | filter --name "a | b"|count`, Commands: []Command{
+					{Args: words("list-users")},
+					{Args: words("filter", "--name", "a | b")},
+					{Args: words("count")},
+				}},
+			}}}},
+		},
+		{
+			line: `report --all > "my report.txt" 2>>errors.log`,
+			expected: List{Items: []AndOr{{Source: `report --all > "my report.txt" 2>>errors.log`, Pipelines: []Pipeline{
+				{Source: `report --all > "my report.txt" 2>>errors.log`, Commands: []Command{
+					{Args: words("report", "--all"), Redirects: []Redirect{
+						{Kind: RedirectOutput, Fd: Stdout, Target: LiteralWord("my report.txt")},
+						{Kind: RedirectAppend, Fd: Stderr, Target: LiteralWord("errors.log")},
+					}},
+				}},
+			}}}},
+		},
+		{
+			line: `import <data.csv | count "2>x"`,
+			expected: List{Items: []AndOr{{Source: `import <data.csv | count "2>x"`, Pipelines: []Pipeline{
+				{Source: `import <data.csv | count "2>x"`, Commands: []Command{
+					{Args: words("import"), Redirects: []Redirect{{Kind: RedirectInput, Fd: Stdin, Target: LiteralWord("data.csv")}}},
+					{Args: words("count", "2>x")},
+				}},
+			}}}},
+		},
+		{
+			line: `build && deploy || rollback "a && b"; status;`,
+			expected: List{Items: []AndOr{
+				{Source: `build && deploy || rollback "a && b"`, Pipelines: []Pipeline{
+					{Condition: Always, Source: "build", Commands: []Command{{Args: words("build")}}},
+					{Condition: OnSuccess, Source: "deploy", Commands: []Command{{Args: words("deploy")}}},
+					{Condition: OnFailure, Source: `rollback "a && b"`, Commands: []Command{{Args: words("rollback", "a && b")}}},
+				}},
+				{Source: "status", Pipelines: []Pipeline{
+					{Condition: Always, Source: "status", Commands: []Command{{Args: words("status")}}},
+				}},
+			}},
+		},
+		{
+			line: "tail -f app.log & build && deploy &",
+			expected: List{Items: []AndOr{
+				{Background: true, Source: "tail -f app.log", Pipelines: []Pipeline{
+					{Condition: Always, Source: "tail -f app.log", Commands: []Command{{Args: words("tail", "-f", "app.log")}}},
+				}},
+				{Background: true, Source: "build && deploy", Pipelines: []Pipeline{
+					{Condition: Always, Source: "build", Commands: []Command{{Args: words("build")}}},
+					{Condition: OnSuccess, Source: "deploy", Commands: []Command{{Args: words("deploy")}}},
+				}},
+			}},
+		},
+		{line: "report >", err: true},
+		{line: "report > | count", err: true},
+		{line: "report 3> out.txt", err: true},
+		{line: "report 2>&1", err: true},
+		{line: "| count", err: true},
+		{line: "list-users |", err: true},
+		{line: "list-users | | count", err: true},
+		{line: "build &&", err: true},
+		{line: "; build", err: true},
+		{line: "build ;; deploy", err: true},
+		{line: "& build", err: true},
+		{line: "build & ; deploy", err: true},
+	}
+
+	for _, test := range tests {
+		got, err := Parse(test.line)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q): expected an error but got %+v", test.line, got)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("Parse(%q): unexpected error %v", test.line, err)
+			continue
+		}
+
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("Parse(%q): expected %+v but got %+v", test.line, test.expected, got)
+		}
+	}
+}
+
+func TestParseFileDescriptorDuplication(t *testing.T) {
+	for _, line := range []string{"report 2>&1", "report >&2", "report > out.txt 2>&1", "import <&3"} {
+		_, err := Parse(line)
+		if err == nil || !strings.Contains(err.Error(), "file descriptor duplication is not supported") {
+			t.Errorf("Parse(%q): expected an error explaining duplication is not supported but got %v", line, err)
+		}
+	}
+}
+
+// words returns the literal words for the given strings
+func words(values ...string) []Word {
+	words := make([]Word, len(values))
+	for i, value := range values {
+		words[i] = LiteralWord(value)
+	}
+	return words
+}
+
+func TestConditionShouldRun(t *testing.T) {
+	tests := []struct {
+		condition         Condition
+		previousSucceeded bool
+		expected          bool
+	}{
+		{Always, true, true},
+		{Always, false, true},
+		{OnSuccess, true, true},
+		{OnSuccess, false, false},
+		{OnFailure, true, false},
+		{OnFailure, false, true},
+	}
+
+	for _, test := range tests {
+		if got := test.condition.ShouldRun(test.previousSucceeded); got != test.expected {
+			t.Errorf("Condition(%d).ShouldRun(%v): expected %v but got %v", test.condition, test.previousSucceeded, test.expected, got)
+		}
+	}
+}
+
+func TestCommandExpandAliases(t *testing.T) {
+	aliases := map[string]string{
+		"dep":   "deploy --env staging",
+		"ls":    "ls -l",
+		"ll":    "ls -a",
+		"home":  "cd $HOME",
+		"piped": "list | count",
+	}
+	lookup := func(name string) (string, bool) {
+		value, found := aliases[name]
+		return value, found
+	}
+
+	tests := []struct {
+		args     []string
+		expected []string
+		err      bool
+	}{
+		{args: []string{"dep", "api"}, expected: []string{"deploy", "--env", "staging", "api"}},
+		{args: []string{"ls"}, expected: []string{"ls", "-l"}},
+		{args: []string{"ll", "/tmp"}, expected: []string{"ls", "-l", "-a", "/tmp"}},
+		{args: []string{"home"}, expected: []string{"cd", "$HOME"}},
+		{args: []string{"echo", "dep"}, expected: []string{"echo", "dep"}},
+		{args: []string{"piped"}, err: true},
+	}
+
+	for _, test := range tests {
+		cmd, err := Command{Args: words(test.args...)}.ExpandAliases(lookup)
+		if test.err {
+			if err == nil {
+				t.Errorf("ExpandAliases(%q): expected an error", test.args)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ExpandAliases(%q): unexpected error: %v", test.args, err)
+			continue
+		}
+
+		got := make([]string, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			got[i] = arg.String()
+		}
+		if !reflect.DeepEqual(got, test.expected) {
+			t.Errorf("ExpandAliases(%q): expected %q but got %q", test.args, test.expected, got)
+		}
+	}
+
+	home, _ := Command{Args: words("home")}.ExpandAliases(lookup)
+	if !home.Args[1].HasVariables() {
+		t.Errorf("expected variables within an alias to be expanded when the command is run")
+	}
+}
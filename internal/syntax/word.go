@@ -0,0 +1,165 @@
+package syntax
+
+import (
+	"strings"
+)
+
+// Word is a word from a line, made up of literal text and references to variables
+type Word []WordPart
+
+// WordPart is a piece of a [Word], which is either literal text or a reference to a variable
+type WordPart struct {
+	Text     string // The literal text, or the name of the variable
+	Variable bool   // If true then Text is the name of a variable to expand
+}
+
+// LiteralWord returns a word made up of just the given literal text
+func LiteralWord(text string) Word {
+	if text == "" {
+		return nil
+	}
+	return Word{{Text: text}}
+}
+
+// Expand returns the word with all variable references replaced
+// by the value returned from lookup.
+//
+// Unlike POSIX shells, the expanded value of a variable is never split into
+// multiple words, so `$name` behaves the same as `"$name"`.
+func (w Word) Expand(lookup func(name string) string) string {
+	var sb strings.Builder
+	for _, part := range w {
+		if part.Variable {
+			sb.WriteString(lookup(part.Text))
+		} else {
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// String returns the word with its variables references unexpanded
+func (w Word) String() string {
+	var sb strings.Builder
+	for i, part := range w {
+		switch {
+		case !part.Variable:
+			sb.WriteString(part.Text)
+		case i+1 < len(w) && !w[i+1].Variable && isNameChar(w[i+1].Text[0]):
+			sb.WriteString("${" + part.Text + "}")
+		default:
+			sb.WriteString("$" + part.Text)
+		}
+	}
+	return sb.String()
+}
+
+// HasVariables returns true if the word contains any variable references
+func (w Word) HasVariables() bool {
+	for _, part := range w {
+		if part.Variable {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidName returns true if name is a valid variable name;
+// a letter or underscore followed by letters, digits or underscores
+func IsValidName(name string) bool {
+	if name == "" || isDigit(name[0]) {
+		return false
+	}
+
+	for i := 0; i < len(name); i++ {
+		if !isNameChar(name[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// readVariable reads the variable reference starting at line[i], which must be a `$`,
+// in the forms `$name`, `${name}` or `$?`.
+//
+// It returns the name of the variable and the index of the last byte of the reference,
+// or ok as false if the `$` does not start a variable reference.
+func readVariable(line string, i int) (name string, end int, ok bool) {
+	if i+1 >= len(line) {
+		return "", i, false
+	}
+
+	switch next := line[i+1]; {
+	case next == '?':
+		return "?", i + 1, true
+
+	case next == '{':
+		closing := strings.IndexByte(line[i+2:], '}')
+		if closing == -1 {
+			return "", i, false
+		}
+
+		name = line[i+2 : i+2+closing]
+		if name != "?" && !IsValidName(name) {
+			return "", i, false
+		}
+		return name, i + 2 + closing, true
+
+	case isNameChar(next) && !isDigit(next):
+		end = i + 1
+		for end+1 < len(line) && isNameChar(line[end+1]) {
+			end++
+		}
+		return line[i+1 : end+1], end, true
+
+	default:
+		return "", i, false
+	}
+}
+
+// wordBuilder builds up a [Word] from its parts
+type wordBuilder struct {
+	parts   Word
+	literal strings.Builder
+}
+
+// writeString appends literal text to the word
+func (b *wordBuilder) writeString(text string) {
+	b.literal.WriteString(text)
+}
+
+// writeByte appends a literal byte to the word
+func (b *wordBuilder) writeByte(c byte) {
+	b.literal.WriteByte(c)
+}
+
+// writeVariable appends a variable reference to the word
+func (b *wordBuilder) writeVariable(name string) {
+	b.flush()
+	b.parts = append(b.parts, WordPart{Text: name, Variable: true})
+}
+
+// flush moves any pending literal text into the parts of the word
+func (b *wordBuilder) flush() {
+	if b.literal.Len() > 0 {
+		b.parts = append(b.parts, WordPart{Text: b.literal.String()})
+		b.literal.Reset()
+	}
+}
+
+// word returns the word built so far and resets the builder
+func (b *wordBuilder) word() Word {
+	b.flush()
+
+	word := b.parts
+	b.parts = nil
+	return word
+}
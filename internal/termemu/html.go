@@ -0,0 +1,142 @@
+package termemu
+
+import (
+	"fmt"
+	"html"
+	"strings"
+)
+
+// RenderHTML returns the output as it would be shown by a terminal, as HTML
+//
+// See [Screen.HTML] for details.
+func RenderHTML(output []byte) string {
+	var s Screen
+	_, _ = s.Write(output)
+	return s.HTML()
+}
+
+// RenderText returns the output as it would be shown by a terminal, without any styling
+func RenderText(output []byte) string {
+	var s Screen
+	_, _ = s.Write(output)
+	return s.Text()
+}
+
+// HTML returns the text on the screen as HTML, with styled text wrapped in spans which
+// set the style with CSS. The text is not wrapped in an element, so should be placed
+// in a <pre> element to keep its layout.
+func (s *Screen) HTML() string {
+	return s.render(func(st style) (start, end string) {
+		css := st.css()
+		if css == "" {
+			return "", ""
+		}
+		return `<span style="` + css + `">`, "</span>"
+	}, func(sb *strings.Builder, r rune) {
+		sb.WriteString(html.EscapeString(string(r)))
+	})
+}
+
+// Text returns the text on the screen without any styling
+func (s *Screen) Text() string {
+	return s.render(func(st style) (start, end string) {
+		return "", ""
+	}, nil)
+}
+
+// css returns the CSS declarations for the style
+func (st style) css() string {
+	var decls []string
+
+	fg, bg := cssColour(st.fg), cssColour(st.bg)
+	if st.attrs&reverse != 0 {
+		if fg == "" {
+			fg = "CanvasText"
+		}
+		if bg == "" {
+			bg = "Canvas"
+		}
+		fg, bg = bg, fg
+	}
+	if fg != "" {
+		decls = append(decls, "color:"+fg)
+	}
+	if bg != "" {
+		decls = append(decls, "background-color:"+bg)
+	}
+
+	if st.attrs&bold != 0 {
+		decls = append(decls, "font-weight:bold")
+	}
+	if st.attrs&faint != 0 {
+		decls = append(decls, "opacity:0.7")
+	}
+	if st.attrs&italic != 0 {
+		decls = append(decls, "font-style:italic")
+	}
+	if st.attrs&hidden != 0 {
+		decls = append(decls, "visibility:hidden")
+	}
+
+	switch {
+	case st.attrs&underline != 0 && st.attrs&strikethrough != 0:
+		decls = append(decls, "text-decoration:underline line-through")
+	case st.attrs&underline != 0:
+		decls = append(decls, "text-decoration:underline")
+	case st.attrs&strikethrough != 0:
+		decls = append(decls, "text-decoration:line-through")
+	}
+
+	return strings.Join(decls, ";")
+}
+
+// basicColours are the RGB values of the 16 basic colours, using the values from xterm
+var basicColours = [16]string{
+	"#000000", "#cd0000", "#00cd00", "#cdcd00", "#0000ee", "#cd00cd", "#00cdcd", "#e5e5e5",
+	"#7f7f7f", "#ff0000", "#00ff00", "#ffff00", "#5c5cff", "#ff00ff", "#00ffff", "#ffffff",
+}
+
+// cssColour returns the CSS colour for the SGR parameters of a colour, as
+// stored in [style], or an empty string for the default colour
+func cssColour(colour string) string {
+	args := parseParams(colour)
+
+	switch {
+	case len(args) == 1 && args[0] >= 30 && args[0] <= 37:
+		return basicColours[args[0]-30]
+	case len(args) == 1 && args[0] >= 40 && args[0] <= 47:
+		return basicColours[args[0]-40]
+	case len(args) == 1 && args[0] >= 90 && args[0] <= 97:
+		return basicColours[args[0]-90+8]
+	case len(args) == 1 && args[0] >= 100 && args[0] <= 107:
+		return basicColours[args[0]-100+8]
+	case len(args) == 3 && args[1] == 5:
+		return colour256(args[2])
+	case len(args) == 5 && args[1] == 2:
+		return fmt.Sprintf("#%02x%02x%02x", args[2]&0xff, args[3]&0xff, args[4]&0xff)
+	default:
+		return ""
+	}
+}
+
+// colour256 returns the RGB value of a colour from the 256 colour palette
+func colour256(n int) string {
+	switch {
+	case n < 0 || n > 255:
+		return ""
+
+	case n < 16:
+		return basicColours[n]
+
+	case n < 232:
+		// A 6x6x6 cube of colours
+		levels := [6]int{0, 95, 135, 175, 215, 255}
+		n -= 16
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/36], levels[(n/6)%6], levels[n%6])
+
+	default:
+		// A ramp of greys
+		grey := 8 + (n-232)*10
+		return fmt.Sprintf("#%02x%02x%02x", grey, grey, grey)
+	}
+}
@@ -0,0 +1,125 @@
+package termemu
+
+import (
+	"strconv"
+	"strings"
+)
+
+// attribute is a text attribute set with an SGR sequence
+type attribute uint16
+
+const (
+	bold attribute = 1 << iota
+	faint
+	italic
+	underline
+	blink
+	reverse
+	hidden
+	strikethrough
+)
+
+// attributeCodes are the SGR parameters which set each attribute
+var attributeCodes = []struct {
+	attr attribute
+	code int
+}{
+	{bold, 1}, {faint, 2}, {italic, 3}, {underline, 4},
+	{blink, 5}, {reverse, 7}, {hidden, 8}, {strikethrough, 9},
+}
+
+// style is the set of SGR attributes and colours text is written with
+//
+// The colours are stored as the SGR parameters which set them, such as
+// "31" or "38;5;200", or an empty string for the default colour.
+type style struct {
+	attrs  attribute
+	fg, bg string
+}
+
+// apply returns the style after applying the parameters of an SGR sequence
+func (st style) apply(args []int) style {
+	if len(args) == 0 {
+		return style{}
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch code := args[i]; {
+		case code == 0:
+			st = style{}
+		case code == 22:
+			st.attrs &^= bold | faint
+		case code >= 23 && code <= 29 && code != 26:
+			for _, a := range attributeCodes {
+				if a.code == code-20 {
+					st.attrs &^= a.attr
+				}
+			}
+		case (code >= 30 && code <= 37) || (code >= 90 && code <= 97):
+			st.fg = strconv.Itoa(code)
+		case code == 39:
+			st.fg = ""
+		case (code >= 40 && code <= 47) || (code >= 100 && code <= 107):
+			st.bg = strconv.Itoa(code)
+		case code == 49:
+			st.bg = ""
+		case code == 38 || code == 48:
+			colour, used := extendedColour(args[i:])
+			i += used - 1
+			if code == 38 {
+				st.fg = colour
+			} else {
+				st.bg = colour
+			}
+		default:
+			for _, a := range attributeCodes {
+				if a.code == code {
+					st.attrs |= a.attr
+				}
+			}
+		}
+	}
+
+	return st
+}
+
+// extendedColour parses a 256 colour (38;5;n) or true colour (38;2;r;g;b) from the start of args,
+// returning the parameters for it and the number of args used
+func extendedColour(args []int) (colour string, used int) {
+	if len(args) >= 3 && args[1] == 5 {
+		return join(args[:3]), 3
+	}
+	if len(args) >= 5 && args[1] == 2 {
+		return join(args[:5]), 5
+	}
+
+	// An invalid colour, so ignore the rest of the sequence
+	return "", len(args)
+}
+
+// sequence returns the SGR sequence which sets the style
+func (st style) sequence() string {
+	var params []string
+	for _, a := range attributeCodes {
+		if st.attrs&a.attr != 0 {
+			params = append(params, strconv.Itoa(a.code))
+		}
+	}
+	if st.fg != "" {
+		params = append(params, st.fg)
+	}
+	if st.bg != "" {
+		params = append(params, st.bg)
+	}
+
+	return "\x1b[" + strings.Join(params, ";") + "m"
+}
+
+// join joins the numbers with semicolons
+func join(args []int) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = strconv.Itoa(arg)
+	}
+	return strings.Join(parts, ";")
+}
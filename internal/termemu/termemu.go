@@ -0,0 +1,404 @@
+// Package termemu is a small terminal emulator for the output of commands, so output
+// written for a terminal, such as progress lines redrawn with a carriage return or
+// text coloured with escape sequences, renders the same within the shell.
+//
+// It handles carriage returns, backspaces, tabs, erasing lines, moving the cursor
+// and SGR colours and text attributes. Any other escape sequences are ignored.
+package termemu
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cockroachdb/errors"
+)
+
+const (
+	esc       = 0x1b
+	tabWidth  = 8
+	maxParams = 16
+	maxParam  = 0xffff // The largest value of a parameter, larger values are clamped to it
+
+	// The furthest escape sequences can move the cursor, like the size of a real terminal, so that a
+	// sequence with a huge parameter can't make the screen grow until it runs out of memory. Text written
+	// past these, such as a long line of output, is still kept.
+	maxRows    = 4096
+	maxColumns = 4096
+
+	// The longest string sequence, such as an operating system command, before it is assumed to be missing
+	// its terminator and dropped, so that a stray sequence can't hide the rest of the output
+	maxStringLength = 4096
+)
+
+// cell is a single character on the screen
+type cell struct {
+	r     rune
+	style uint16 // The index of the style of the cell in [Screen.styles]
+}
+
+// Screen is the state of the emulated terminal, which output is written to.
+//
+// Unlike a real terminal the screen has no height, so lines are never
+// scrolled off the top of it. The zero value is an empty screen ready to use.
+type Screen struct {
+	lines    [][]cell
+	row, col int
+
+	style   style         // The current style new characters are written with
+	styles  []style       // The styles used by the cells on the screen
+	styleID map[style]int // The index of each style in styles
+	pending []byte        // An incomplete escape sequence or rune from the end of the last write
+
+	inString  bool // Whether the output is within a string sequence, which has no effect on the screen
+	stringLen int  // The number of bytes of the string sequence seen so far
+	stringEsc bool // Whether the last byte of the string sequence was an ESC, which starts its terminator
+}
+
+// Render returns the output as it would be shown by a terminal
+func Render(output []byte) string {
+	var s Screen
+	_, _ = s.Write(output)
+	return s.String()
+}
+
+// Write implements [io.Writer], processing the output as a terminal would
+func (s *Screen) Write(p []byte) (n int, err error) {
+	data := p
+	if len(s.pending) > 0 {
+		data = append(s.pending, p...)
+		s.pending = nil
+	}
+
+	for i := 0; i < len(data); {
+		if s.inString {
+			i += s.skipString(data[i:])
+			continue
+		}
+
+		b := data[i]
+
+		switch {
+		case b == esc:
+			consumed, complete := s.escape(data[i:])
+			if !complete {
+				s.pending = append([]byte(nil), data[i:]...)
+				return len(p), nil
+			}
+			i += consumed
+			continue
+
+		case b == '\n':
+			s.row++
+			s.col = 0
+		case b == '\r':
+			s.col = 0
+		case b == '\b':
+			if s.col > 0 {
+				s.col--
+			}
+		case b == '\t':
+			s.col = (s.col/tabWidth + 1) * tabWidth
+		case b < 0x20 || b == 0x7f:
+			// Ignore any other control characters, such as the bell
+
+		default:
+			if !utf8.FullRune(data[i:]) {
+				s.pending = append([]byte(nil), data[i:]...)
+				return len(p), nil
+			}
+
+			r, size := utf8.DecodeRune(data[i:])
+			s.put(r)
+			i += size
+			continue
+		}
+		i++
+	}
+
+	return len(p), nil
+}
+
+// escape processes the escape sequence at the start of data, returning the number of bytes
+// it used, or false if data ends before the end of the sequence
+func (s *Screen) escape(data []byte) (consumed int, complete bool) {
+	if len(data) < 2 {
+		return 0, false
+	}
+
+	switch data[1] {
+	case '[':
+		// A control sequence; ESC [ params intermediates final
+		for i := 2; i < len(data); i++ {
+			if data[i] >= 0x40 && data[i] <= 0x7e {
+				s.control(string(data[2:i]), data[i])
+				return i + 1, true
+			}
+		}
+		return 0, false
+
+	case ']', 'P', '_', '^':
+		// An operating system command or other string, which is skipped by [Screen.skipString]
+		s.inString = true
+		s.stringLen = 0
+		s.stringEsc = false
+		return 2, true
+
+	case '(', ')', '*', '+', '#', '%':
+		// Character set selection, which has one more byte
+		if len(data) < 3 {
+			return 0, false
+		}
+		return 3, true
+
+	default:
+		return 2, true
+	}
+}
+
+// skipString skips over the body of a string sequence, which ends with BEL or ESC \, returning
+// the number of bytes it used. A string longer than [maxStringLength] is dropped, and the bytes
+// after it are processed as normal output.
+func (s *Screen) skipString(data []byte) (consumed int) {
+	for i, b := range data {
+		if s.stringLen == maxStringLength {
+			s.inString = false
+			return i
+		}
+		s.stringLen++
+
+		switch {
+		case s.stringEsc, b == 0x07:
+			s.inString = false
+			return i + 1
+		case b == esc:
+			s.stringEsc = true
+		}
+	}
+	return len(data)
+}
+
+// control processes a control sequence with the given parameters and final byte
+func (s *Screen) control(params string, final byte) {
+	// Private sequences, such as hiding the cursor, have no effect on the output
+	if params != "" && (params[0] < '0' || params[0] > '9') && params[0] != ';' {
+		return
+	}
+
+	args := parseParams(params)
+	arg := func(i, def int) int {
+		if i < len(args) && args[i] > 0 {
+			return args[i]
+		}
+		return def
+	}
+
+	switch final {
+	case 'm':
+		s.style = s.style.apply(args)
+	case 'A':
+		s.row = atLeastZero(s.row - arg(0, 1))
+	case 'B':
+		s.row = limitMove(s.row+arg(0, 1), s.row, maxRows-1)
+	case 'C':
+		s.col = limitMove(s.col+arg(0, 1), s.col, maxColumns-1)
+	case 'D':
+		s.col = atLeastZero(s.col - arg(0, 1))
+	case 'E':
+		s.row = limitMove(s.row+arg(0, 1), s.row, maxRows-1)
+		s.col = 0
+	case 'F':
+		s.row = atLeastZero(s.row - arg(0, 1))
+		s.col = 0
+	case 'G':
+		s.col = limitMove(arg(0, 1)-1, s.col, maxColumns-1)
+	case 'H', 'f':
+		s.row = limitMove(arg(0, 1)-1, s.row, maxRows-1)
+		s.col = limitMove(arg(1, 1)-1, s.col, maxColumns-1)
+	case 'K':
+		s.eraseLine(arg(0, 0))
+	case 'J':
+		s.eraseDisplay(arg(0, 0))
+	}
+}
+
+// eraseLine erases part of the line the cursor is on
+//
+//	0: from the cursor to the end of the line
+//	1: from the start of the line to the cursor
+//	2: the whole line
+func (s *Screen) eraseLine(mode int) {
+	if s.row >= len(s.lines) {
+		return
+	}
+	line := s.lines[s.row]
+
+	switch mode {
+	case 0:
+		if s.col < len(line) {
+			s.lines[s.row] = line[:s.col]
+		}
+	case 1:
+		for i := 0; i <= s.col && i < len(line); i++ {
+			line[i] = cell{r: ' '}
+		}
+	case 2:
+		s.lines[s.row] = nil
+	}
+}
+
+// eraseDisplay erases part of the screen
+//
+//	0: from the cursor to the end of the screen
+//	1: from the start of the screen to the cursor
+//	2, 3: the whole screen
+func (s *Screen) eraseDisplay(mode int) {
+	switch mode {
+	case 0:
+		s.eraseLine(0)
+		if s.row+1 < len(s.lines) {
+			s.lines = s.lines[:s.row+1]
+		}
+	case 1:
+		for i := 0; i < s.row && i < len(s.lines); i++ {
+			s.lines[i] = nil
+		}
+		s.eraseLine(1)
+	case 2, 3:
+		s.lines = nil
+	}
+}
+
+// put writes the rune at the cursor, and moves the cursor on
+func (s *Screen) put(r rune) {
+	for len(s.lines) <= s.row {
+		s.lines = append(s.lines, nil)
+	}
+
+	line := s.lines[s.row]
+	for len(line) <= s.col {
+		line = append(line, cell{r: ' '})
+	}
+	line[s.col] = cell{r: r, style: s.styleIndex(s.style)}
+	s.lines[s.row] = line
+	s.col++
+}
+
+// styleIndex returns the index of the style in s.styles, adding it if needed
+func (s *Screen) styleIndex(st style) uint16 {
+	if st == (style{}) {
+		return 0
+	}
+
+	if s.styleID == nil {
+		s.styles = []style{{}}
+		s.styleID = map[style]int{{}: 0}
+	}
+
+	id, found := s.styleID[st]
+	if !found {
+		if len(s.styles) > 0xffff {
+			// Too many styles have been used, so fallback to no styling
+			return 0
+		}
+
+		id = len(s.styles)
+		s.styles = append(s.styles, st)
+		s.styleID[st] = id
+	}
+	return uint16(id)
+}
+
+// String returns the text on the screen, with SGR sequences for any styled text
+func (s *Screen) String() string {
+	return s.render(func(st style) (start, end string) {
+		return st.sequence(), "\x1b[0m"
+	}, nil)
+}
+
+// render returns the text on the screen, surrounding each run of styled text with the
+// start and end returned by format and writing each character with escape if it's given
+func (s *Screen) render(format func(st style) (start, end string), escape func(sb *strings.Builder, r rune)) string {
+	var sb strings.Builder
+
+	for i, line := range s.lines {
+		if i > 0 {
+			sb.WriteByte('\n')
+		}
+
+		// Unstyled spaces at the end of a line are not shown
+		end := len(line)
+		for end > 0 && line[end-1].r == ' ' && line[end-1].style == 0 {
+			end--
+		}
+
+		var current uint16
+		var currentEnd string
+		for _, c := range line[:end] {
+			if c.style != current {
+				sb.WriteString(currentEnd)
+				currentEnd = ""
+				if c.style != 0 {
+					var start string
+					start, currentEnd = format(s.styles[c.style])
+					sb.WriteString(start)
+				}
+				current = c.style
+			}
+
+			if escape != nil {
+				escape(&sb, c.r)
+			} else {
+				sb.WriteRune(c.r)
+			}
+		}
+		sb.WriteString(currentEnd)
+	}
+
+	return sb.String()
+}
+
+// parseParams parses the semicolon separated parameters of a control sequence,
+// with missing parameters being returned as zero and values over maxParam clamped to it
+func parseParams(params string) []int {
+	if params == "" {
+		return nil
+	}
+
+	parts := strings.Split(params, ";")
+	if len(parts) > maxParams {
+		parts = parts[:maxParams]
+	}
+
+	args := make([]int, len(parts))
+	for i, part := range parts {
+		// Sub parameters separated by colons are not supported, so just use the first value
+		part, _, _ = strings.Cut(part, ":")
+		value, err := strconv.Atoi(part)
+		if value > maxParam || errors.Is(err, strconv.ErrRange) {
+			value = maxParam
+		}
+		args[i] = atLeastZero(value)
+	}
+	return args
+}
+
+// limitMove returns the position an escape sequence moves the cursor to along one axis, which is
+// at most limit, unless text has already been written past it, in which case the cursor can't move further
+func limitMove(target, current, limit int) int {
+	if target <= limit || target <= current {
+		return target
+	}
+	if current > limit {
+		return current
+	}
+	return limit
+}
+
+// atLeastZero returns n, or zero if n is negative
+func atLeastZero(n int) int {
+	if n < 0 {
+		return 0
+	}
+	return n
+}
@@ -0,0 +1,132 @@
+package termemu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{name: "plain text", output: "hello\nworld\n", expected: "hello\nworld"},
+		{name: "carriage return", output: "10%\r50%\r100%\ndone", expected: "100%\ndone"},
+		{name: "carriage return overwrites", output: "loading...\rok", expected: "okading..."},
+		{name: "backspace", output: "abc\b\bX", expected: "aXc"},
+		{name: "tab", output: "a\tb", expected: "a       b"},
+		{name: "erase to end of line", output: "loading...\rok\x1b[K", expected: "ok"},
+		{name: "erase whole line", output: "progress\x1b[2K\rdone", expected: "done"},
+		{name: "erase start of line", output: "abcdef\x1b[3D\x1b[1K", expected: "    ef"},
+		{name: "cursor up", output: "a\nb\n\x1b[2Ax", expected: "x\nb"},
+		{name: "cursor position", output: "abc\ndef\x1b[1;2HX", expected: "aXc\ndef"},
+		{name: "cursor column", output: "abcdef\x1b[3GX", expected: "abXdef"},
+		{name: "erase display", output: "a\nb\x1b[2Jc", expected: "\n c"},
+		{name: "ignores other sequences", output: "\x1b[?25lhi\x1b[?25h\x1b]0;title\x07!", expected: "hi!"},
+		{name: "ignores control characters", output: "a\x07b", expected: "ab"},
+		{name: "colours", output: "\x1b[31mred\x1b[0m plain", expected: "\x1b[31mred\x1b[0m plain"},
+		{name: "attributes and colours", output: "\x1b[1;38;5;200mhi\x1b[22mx\x1b[m", expected: "\x1b[1;38;5;200mhi\x1b[0m\x1b[38;5;200mx\x1b[0m"},
+		{name: "colour reset per line", output: "\x1b[32ma\nb", expected: "\x1b[32ma\x1b[0m\n\x1b[32mb\x1b[0m"},
+		{name: "colour overwritten", output: "\x1b[31mred\x1b[0m\rok", expected: "ok\x1b[31md\x1b[0m"},
+		{name: "unicode", output: "héllo\r✓", expected: "✓éllo"},
+	}
+
+	for _, test := range tests {
+		if got := Render([]byte(test.output)); got != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.name, test.expected, got)
+		}
+	}
+}
+
+func TestScreenSplitWrites(t *testing.T) {
+	output := []byte("\x1b[31mcolour\x1b[0m ✓ \x1b]0;title\x1b\\done\r\x1b[K!")
+	expected := Render(output)
+
+	// Writing the output a byte at a time should give the same result
+	var s Screen
+	for i := range output {
+		_, _ = s.Write(output[i : i+1])
+	}
+
+	if got := s.String(); got != expected {
+		t.Errorf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestUnterminatedStringSequence(t *testing.T) {
+	text := strings.Repeat("a", maxStringLength)
+
+	// The sequence is dropped once it is too long, so the rest of the output is still shown
+	var s Screen
+	_, _ = s.Write([]byte("before\x1b]"))
+	for i := 0; i < 2; i++ {
+		_, _ = s.Write([]byte(text))
+	}
+	_, _ = s.Write([]byte("\nafter"))
+
+	if got := s.String(); got != "before"+text+"\nafter" {
+		t.Errorf("expected the output after the unterminated sequence to be shown but got %q", got)
+	}
+	if len(s.pending) != 0 {
+		t.Errorf("expected the sequence not to be kept but got %d pending bytes", len(s.pending))
+	}
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{name: "escapes text", output: "<b> & \"quotes\"", expected: "&lt;b&gt; &amp; &#34;quotes&#34;"},
+		{name: "basic colour", output: "\x1b[31mred\x1b[0m plain", expected: `<span style="color:#cd0000">red</span> plain`},
+		{name: "256 colour", output: "\x1b[1;38;5;205mpink", expected: `<span style="color:#ff5faf;font-weight:bold">pink</span>`},
+		{name: "true colour background", output: "\x1b[48;2;1;2;3mbg", expected: `<span style="background-color:#010203">bg</span>`},
+		{name: "grey", output: "\x1b[38;5;244mgrey", expected: `<span style="color:#808080">grey</span>`},
+		{name: "reverse", output: "\x1b[7mrev", expected: `<span style="color:Canvas;background-color:CanvasText">rev</span>`},
+		{name: "per line", output: "\x1b[32ma\nb", expected: "<span style=\"color:#00cd00\">a</span>\n<span style=\"color:#00cd00\">b</span>"},
+	}
+
+	for _, test := range tests {
+		if got := RenderHTML([]byte(test.output)); got != test.expected {
+			t.Errorf("%s: expected %q but got %q", test.name, test.expected, got)
+		}
+	}
+
+	if got := RenderText([]byte("\x1b[31mred\x1b[0m\rR")); got != "Red" {
+		t.Errorf("expected the text without styling but got %q", got)
+	}
+}
+
+func TestHugeCursorMovements(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		rows   int
+		cols   int
+	}{
+		{name: "cursor forward", output: "\x1b[2147483647Cx", rows: 1, cols: maxColumns},
+		{name: "out of range parameter", output: "\x1b[99999999999999999999Cx", rows: 1, cols: maxColumns},
+		{name: "cursor down", output: "\x1b[2147483647Bx", rows: maxRows, cols: 1},
+		{name: "cursor next line", output: "\x1b[99999999999999999999Ex", rows: maxRows, cols: 1},
+		{name: "cursor position", output: "\x1b[2147483647;2147483647Hx", rows: maxRows, cols: maxColumns},
+		{name: "cursor column", output: "\x1b[99999999999999999999Gx", rows: 1, cols: maxColumns},
+		{name: "repeated moves", output: "\x1b[65535C\x1b[65535C\x1b[65535Cx", rows: 1, cols: maxColumns},
+	}
+
+	for _, test := range tests {
+		var s Screen
+		_, _ = s.Write([]byte(test.output))
+
+		if len(s.lines) != test.rows || len(s.lines[len(s.lines)-1]) != test.cols {
+			t.Errorf("%s: expected %d rows with %d columns on the last but got %d rows with %d columns", test.name, test.rows, test.cols, len(s.lines), len(s.lines[len(s.lines)-1]))
+		}
+	}
+
+	// Long lines of text are still kept in full, but escape sequences can't move the cursor further past the limit
+	line := strings.Repeat("a", maxColumns*2)
+	if got := Render([]byte(line + "\x1b[5Cb")); got != line+"b" {
+		t.Errorf("expected the long line to be kept with the cursor not moved but got %d characters", len(got))
+	}
+}
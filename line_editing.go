@@ -0,0 +1,307 @@
+package shell
+
+import (
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// killRingSize is the number of cuts the kill ring keeps, after which the oldest are dropped
+const killRingSize = 32
+
+// editKind is the kind of change a key press made to the input
+type editKind uint8
+
+const (
+	editMove   editKind = iota // The cursor moved, or nothing changed
+	editInsert                 // Characters were typed
+	editDelete                 // Characters were deleted one at a time
+	editKill                   // Text was cut into the kill ring
+	editYank                   // Text was pasted from the kill ring
+	editOther                  // Any other change, such as transposing characters
+	editUndo                   // A change was undone or redone
+)
+
+// inputState is the value of the input and the position of the cursor within it
+type inputState struct {
+	value string
+	pos   int
+}
+
+func stateOf(input textinput.Model) inputState {
+	return inputState{value: input.Value(), pos: input.Position()}
+}
+
+// lineEditor adds readline style editing to the command input; text can be cut into a
+// kill ring and pasted back from it, and changes to the input can be undone and redone.
+//
+// It is shared between the copies of the model, as they are passed by value.
+type lineEditor struct {
+	killRing  []string
+	yankIndex int // The index in the kill ring of the text last pasted
+	yankStart int // The position in the input of the start of the text last pasted
+	yankEnd   int // The position in the input of the end of the text last pasted
+
+	undo []inputState
+	redo []inputState
+
+	last     inputState // The state of the input after the last key press, to spot changes made by the shell
+	lastEdit editKind
+}
+
+func newLineEditor() *lineEditor {
+	return &lineEditor{}
+}
+
+// updateInput passes the message to the input, with any line editing key presses handled by the editor
+func (m Model) updateInput(msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok || !m.input.Focused() || m.lineEditor == nil {
+		var cmd tea.Cmd
+		m.input, cmd = m.input.Update(msg)
+		return m, cmd
+	}
+
+	e := m.lineEditor
+	before := stateOf(m.input)
+
+	// If the shell has set the input, such as clearing it once a command is run,
+	// changes made before then can no longer be undone
+	if before.value != e.last.value {
+		e.undo, e.redo = nil, nil
+		e.last = before
+		e.lastEdit = editMove
+	}
+
+	// In the vi normal and visual modes key presses are commands, which are handled by the mode
+	switch m.mode.(type) {
+	case *ViNormalMode, *ViVisualMode:
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	kind, handled := e.handleKey(m.cfg.KeyMap, &m.input, keyMsg)
+	if !handled {
+		// Characters typed while holding alt are key bindings, not text for the input
+		if keyMsg.Alt && keyMsg.Type == tea.KeyRunes {
+			return m, nil
+		}
+
+		m.input, cmd = m.input.Update(msg)
+
+		after := m.input.Value()
+		switch {
+		case len(after) > len(before.value):
+			kind = editInsert
+		case len(after) < len(before.value):
+			kind = editDelete
+		case after != before.value:
+			kind = editOther
+		}
+	}
+
+	e.record(before, stateOf(m.input), kind)
+	return m, cmd
+}
+
+// handleKey handles the key press if it is a line editing binding, returning the kind of change made
+func (e *lineEditor) handleKey(keyMap KeyMap, input *textinput.Model, msg tea.KeyMsg) (kind editKind, handled bool) {
+	value := []rune(input.Value())
+	pos := input.Position()
+
+	switch {
+	case key.Matches(msg, keyMap.WordForward):
+		input.SetCursor(nextWordEnd(value, pos))
+		return editMove, true
+
+	case key.Matches(msg, keyMap.WordBackward):
+		input.SetCursor(previousWordStart(value, pos))
+		return editMove, true
+
+	case key.Matches(msg, keyMap.KillWordBackward):
+		// Like readline, words are separated by whitespace when cutting backwards
+		start := pos
+		for start > 0 && unicode.IsSpace(value[start-1]) {
+			start--
+		}
+		for start > 0 && !unicode.IsSpace(value[start-1]) {
+			start--
+		}
+		return e.kill(input, value, start, pos, true), true
+
+	case key.Matches(msg, keyMap.KillWordForward):
+		return e.kill(input, value, pos, nextWordEnd(value, pos), false), true
+
+	case key.Matches(msg, keyMap.KillToStart):
+		return e.kill(input, value, 0, pos, true), true
+
+	case key.Matches(msg, keyMap.KillToEnd):
+		return e.kill(input, value, pos, len(value), false), true
+
+	case key.Matches(msg, keyMap.Yank):
+		if len(e.killRing) == 0 {
+			return editMove, true
+		}
+
+		e.yankIndex = len(e.killRing) - 1
+		e.yank(input, value, pos, pos)
+		return editYank, true
+
+	case key.Matches(msg, keyMap.YankPop):
+		// Yank pop only replaces text which has just been pasted
+		if e.lastEdit != editYank || len(e.killRing) < 2 {
+			return editMove, true
+		}
+
+		e.yankIndex = (e.yankIndex - 1 + len(e.killRing)) % len(e.killRing)
+		e.yank(input, value, e.yankStart, e.yankEnd)
+		return editYank, true
+
+	case key.Matches(msg, keyMap.TransposeChars):
+		if len(value) < 2 || pos == 0 {
+			return editMove, true
+		}
+
+		// At the end of the line the two characters before the cursor are swapped
+		if pos == len(value) {
+			pos--
+		}
+		value[pos-1], value[pos] = value[pos], value[pos-1]
+		setInput(input, inputState{value: string(value), pos: pos + 1})
+		return editOther, true
+
+	case key.Matches(msg, keyMap.Undo):
+		e.undoChange(input)
+		return editUndo, true
+
+	case key.Matches(msg, keyMap.Redo):
+		e.redoChange(input)
+		return editUndo, true
+	}
+
+	return editMove, false
+}
+
+// kill cuts the runes from start to end out of the input into the kill ring
+//
+// Consecutive kills are joined into a single entry in the kill ring, so they can be
+// pasted back together, with text cut backwards added before the text already cut.
+func (e *lineEditor) kill(input *textinput.Model, value []rune, start, end int, backwards bool) editKind {
+	if start >= end {
+		return editMove
+	}
+	text := string(value[start:end])
+
+	switch {
+	case e.lastEdit == editKill && len(e.killRing) > 0 && backwards:
+		e.killRing[len(e.killRing)-1] = text + e.killRing[len(e.killRing)-1]
+	case e.lastEdit == editKill && len(e.killRing) > 0:
+		e.killRing[len(e.killRing)-1] += text
+	default:
+		e.cut(text)
+	}
+
+	setInput(input, inputState{value: string(value[:start]) + string(value[end:]), pos: start})
+	return editKill
+}
+
+// cut adds the text to the kill ring as a new entry
+func (e *lineEditor) cut(text string) {
+	e.killRing = append(e.killRing, text)
+	if len(e.killRing) > killRingSize {
+		e.killRing = e.killRing[1:]
+	}
+}
+
+// yank replaces the runes from start to end of the input with the current entry of the kill ring
+func (e *lineEditor) yank(input *textinput.Model, value []rune, start, end int) {
+	text := []rune(e.killRing[e.yankIndex])
+
+	e.yankStart = start
+	e.yankEnd = start + len(text)
+	setInput(input, inputState{value: string(value[:start]) + string(text) + string(value[end:]), pos: e.yankEnd})
+}
+
+// record records the change made to the input by a key press, so it can be undone.
+// Consecutive characters typed or deleted are undone together.
+func (e *lineEditor) record(before, after inputState, kind editKind) {
+	if kind != editUndo && before.value != after.value {
+		if kind != e.lastEdit || (kind != editInsert && kind != editDelete) {
+			e.undo = append(e.undo, before)
+		}
+		e.redo = nil
+	}
+
+	e.last = after
+	e.lastEdit = kind
+}
+
+// change sets the input to the state after a change made other than by typing, such as by
+// a vi command, recording the change so it can be undone
+func (e *lineEditor) change(input *textinput.Model, after inputState, kind editKind) {
+	before := stateOf(*input)
+	setInput(input, after)
+	e.record(before, stateOf(*input), kind)
+}
+
+// undoChange undoes the last change to the input, returning false if there was nothing to undo
+func (e *lineEditor) undoChange(input *textinput.Model) bool {
+	if len(e.undo) == 0 {
+		return false
+	}
+
+	e.redo = append(e.redo, stateOf(*input))
+	setInput(input, e.undo[len(e.undo)-1])
+	e.undo = e.undo[:len(e.undo)-1]
+	e.last = stateOf(*input)
+	return true
+}
+
+// redoChange redoes the last change to the input which was undone, returning false if there was nothing to redo
+func (e *lineEditor) redoChange(input *textinput.Model) bool {
+	if len(e.redo) == 0 {
+		return false
+	}
+
+	e.undo = append(e.undo, stateOf(*input))
+	setInput(input, e.redo[len(e.redo)-1])
+	e.redo = e.redo[:len(e.redo)-1]
+	e.last = stateOf(*input)
+	return true
+}
+
+// setInput sets the value of the input and the position of the cursor
+func setInput(input *textinput.Model, state inputState) {
+	input.SetValue(state.value)
+	input.SetCursor(state.pos)
+}
+
+// isWordRune reports whether the rune is part of a word when moving by words,
+// which like readline are made up of letters and digits
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// nextWordEnd returns the position of the end of the word after pos
+func nextWordEnd(value []rune, pos int) int {
+	for pos < len(value) && !isWordRune(value[pos]) {
+		pos++
+	}
+	for pos < len(value) && isWordRune(value[pos]) {
+		pos++
+	}
+	return pos
+}
+
+// previousWordStart returns the position of the start of the word before pos
+func previousWordStart(value []rune, pos int) int {
+	for pos > 0 && !isWordRune(value[pos-1]) {
+		pos--
+	}
+	for pos > 0 && isWordRune(value[pos-1]) {
+		pos--
+	}
+	return pos
+}
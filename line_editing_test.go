@@ -0,0 +1,79 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+var (
+	ctrlW     = tea.KeyMsg{Type: tea.KeyCtrlW}
+	ctrlK     = tea.KeyMsg{Type: tea.KeyCtrlK}
+	ctrlU     = tea.KeyMsg{Type: tea.KeyCtrlU}
+	ctrlY     = tea.KeyMsg{Type: tea.KeyCtrlY}
+	ctrlT     = tea.KeyMsg{Type: tea.KeyCtrlT}
+	ctrlA     = tea.KeyMsg{Type: tea.KeyCtrlA}
+	ctrlE     = tea.KeyMsg{Type: tea.KeyCtrlE}
+	undo      = tea.KeyMsg{Type: tea.KeyCtrlUnderscore}
+	backspace = tea.KeyMsg{Type: tea.KeyBackspace}
+)
+
+func alt(r rune) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{r}, Alt: true}
+}
+
+func typed(text string) tea.KeyMsg {
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)}
+}
+
+func TestLineEditing(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []tea.KeyMsg
+		expected string
+		pos      int
+	}{
+		{name: "kill word backward", keys: []tea.KeyMsg{typed("deploy --env prod"), ctrlW}, expected: "deploy --env ", pos: 13},
+		{name: "kill word forward", keys: []tea.KeyMsg{typed("deploy --env prod"), ctrlA, alt('d')}, expected: " --env prod", pos: 0},
+		{name: "word movement", keys: []tea.KeyMsg{typed("deploy --env prod"), alt('b'), alt('b'), typed("x")}, expected: "deploy --xenv prod", pos: 10},
+		{name: "word forward", keys: []tea.KeyMsg{typed("deploy --env prod"), ctrlA, alt('f'), alt('f'), typed("x")}, expected: "deploy --envx prod", pos: 13},
+		{name: "yank", keys: []tea.KeyMsg{typed("deploy api"), ctrlW, ctrlA, ctrlY, typed(" ")}, expected: "api deploy ", pos: 4},
+		{name: "consecutive kills", keys: []tea.KeyMsg{typed("a b c"), ctrlW, ctrlW, ctrlY}, expected: "a b c", pos: 5},
+		{name: "yank pop", keys: []tea.KeyMsg{typed("one two"), ctrlW, alt('b'), ctrlK, ctrlY, alt('y')}, expected: "two", pos: 3},
+		{name: "kill to start", keys: []tea.KeyMsg{typed("deploy api"), alt('b'), ctrlU, ctrlE, ctrlY}, expected: "apideploy ", pos: 10},
+		{name: "transpose", keys: []tea.KeyMsg{typed("dpeloy"), ctrlA, tea.KeyMsg{Type: tea.KeyRight}, tea.KeyMsg{Type: tea.KeyRight}, ctrlT}, expected: "deploy", pos: 3},
+		{name: "transpose at end", keys: []tea.KeyMsg{typed("deplyo"), ctrlT}, expected: "deploy", pos: 6},
+		{name: "alt keys are not typed", keys: []tea.KeyMsg{typed("ls"), alt('c')}, expected: "ls", pos: 2},
+		{name: "undo typing", keys: []tea.KeyMsg{typed("de"), typed("ploy"), ctrlW, typed("status"), undo}, expected: "", pos: 0},
+		{name: "undo kill", keys: []tea.KeyMsg{typed("deploy api"), ctrlW, typed("web"), backspace, undo, undo, undo}, expected: "deploy api", pos: 10},
+		{name: "redo", keys: []tea.KeyMsg{typed("deploy api"), ctrlW, undo, alt('_')}, expected: "deploy ", pos: 7},
+	}
+
+	for _, test := range tests {
+		m := newModel(config.Default(), &cobra.Command{})
+		for _, msg := range test.keys {
+			m, _ = m.updateInput(msg)
+		}
+
+		if got := m.input.Value(); got != test.expected || m.input.Position() != test.pos {
+			t.Errorf("%s: expected %q with the cursor at %d but got %q with the cursor at %d", test.name, test.expected, test.pos, got, m.input.Position())
+		}
+	}
+}
+
+func TestUndoResetWhenInputSet(t *testing.T) {
+	m := newModel(config.Default(), &cobra.Command{})
+	m, _ = m.updateInput(typed("deploy"))
+
+	// The input is cleared when a command is run, after which the old command can't be restored
+	m.input.SetValue("")
+	m, _ = m.updateInput(typed("ls"))
+	m, _ = m.updateInput(undo)
+	m, _ = m.updateInput(undo)
+
+	if got := m.input.Value(); got != "" {
+		t.Errorf("expected undo to stop at the empty input but got %q", got)
+	}
+}
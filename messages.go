@@ -3,22 +3,76 @@ package shell
 import (
 	"context"
 
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/stdin"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/DomBlack/bubble-shell/pkg/interact"
 	"github.com/DomBlack/bubble-shell/pkg/modelid"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
 )
 
 // This message is sent when a new command is being run
 // back to the main model, which allows the main model to
 // trigger a cancel on the context of the command which
-// is currently running.
+// is currently running, and send it the lines the user types.
 type currentCmdContextCancelFuncMsg struct {
 	id     modelid.ID
 	cancel context.CancelFunc
+	input  *stdin.Input
 }
 
 func (msg currentCmdContextCancelFuncMsg) ForModelID() modelid.ID {
 	return msg.id
 }
 
+// runJobMsg is sent to start running a background job once
+// the history item for the job has been added to the history
+type runJobMsg struct {
+	id   modelid.ID
+	ctx  context.Context
+	job  *session.Job
+	item history.Item
+	list syntax.AndOr
+}
+
+func (msg runJobMsg) ForModelID() modelid.ID {
+	return msg.id
+}
+
+// askQuestionMsg is sent when the running command asks the user
+// a question using the [interact] package
+type askQuestionMsg struct {
+	id      modelid.ID
+	request *interact.Request
+}
+
+func (msg askQuestionMsg) ForModelID() modelid.ID {
+	return msg.id
+}
+
+// shellRequestMsg is sent when a builtin command needs
+// to read or change the state of the shell
+type shellRequestMsg struct {
+	id modelid.ID
+	fn func(m Model) (Model, tea.Cmd)
+}
+
+func (msg shellRequestMsg) ForModelID() modelid.ID {
+	return msg.id
+}
+
+// takeoverQuitMsg is sent when the model a command has handed
+// the screen over to quits
+type takeoverQuitMsg struct {
+	id      modelid.ID
+	request *interact.Request
+}
+
+func (msg takeoverQuitMsg) ForModelID() modelid.ID {
+	return msg.id
+}
+
 // enterModeMsg tells the shell mode to enter into given mode
 type enterModeMsg struct {
 	ID   modelid.ID
@@ -28,6 +28,12 @@ type Mode interface {
 	FullHelp(m Model, keyMap KeyMap) [][]key.Binding
 }
 
+// fullScreenMode is implemented by modes which replace the whole view of the shell
+// with their [Mode.AdditionalView], rather than adding it below the history
+type fullScreenMode interface {
+	fullScreen()
+}
+
 // Enter tells the mode to enter the given mode leaving the old mode
 func (m Model) Enter(mode Mode) tea.Cmd {
 	return func() tea.Msg {
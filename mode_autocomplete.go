@@ -1,8 +1,7 @@
 package shell
 
 import (
-	"strings"
-
+	"github.com/DomBlack/bubble-shell/internal/syntax"
 	"github.com/DomBlack/bubble-shell/pkg/tui/autocomplete"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
@@ -77,14 +76,10 @@ func (a *AutoCompleteMode) AcceptOption(m Model) (Model, tea.Cmd) {
 	if suggestion := m.autocomplete.Accept(); suggestion != "" {
 		input := m.input.Value()
 
-		// Cut off the last word so we can replace it with the autocomplete
-		if idx := strings.LastIndexByte(input, ' '); idx == -1 {
-			input = ""
-		} else {
-			input = input[:idx+1]
-		}
+		// Cut off the word being completed so we can replace it with the autocomplete
+		_, wordStart := syntax.CompletionArgs(input)
 
-		m.input.SetValue(input + suggestion + " ")
+		m.input.SetValue(input[:wordStart] + suggestion + " ")
 		m.input.CursorEnd()
 	}
 
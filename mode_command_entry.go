@@ -5,10 +5,14 @@ import (
 
 	"github.com/DomBlack/bubble-shell/pkg/tui/history"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-type CommandEntryMode struct{ KeepInputContent bool }
+type CommandEntryMode struct {
+	KeepInputContent   bool
+	KeepCursorPosition bool // Leaves the cursor where it is in the input rather than moving it to the end, used with KeepInputContent
+}
 
 var _ Mode = (*CommandEntryMode)(nil)
 
@@ -18,10 +22,18 @@ func (c *CommandEntryMode) Enter(m Model) (Model, tea.Cmd) {
 	}
 
 	m.lookBackPartial = ""
+	m.currentCmdInput = nil
 	m.input.Prompt = m.cfg.PromptFunc()
-	m.input.CursorEnd()
+	m.input.Placeholder = inputPlaceholder
+	m.input.EchoMode = textinput.EchoNormal
 	m.input.Focus()
 
+	// Unless a vi command entered insert mode, this is a fresh start at typing
+	if !c.KeepCursorPosition {
+		m.input.CursorEnd()
+		m.vi.insert = nil
+	}
+
 	return m, nil
 }
 
@@ -68,6 +80,7 @@ func (c *CommandEntryMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 			historyItem := history.NewItem(m.input.Prompt, line, history.RunningStatus)
 			m.input.SetValue("")
 			m.input.CursorEnd()
+			m.history.ScrollToBottom()
 
 			return m, tea.Sequence(
 				m.Enter(&CommandRunningMode{}),
@@ -84,6 +97,27 @@ func (c *CommandEntryMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 		case key.Matches(msg, m.cfg.KeyMap.AutoComplete):
 			return m, m.Enter(&AutoCompleteMode{})
 
+		case key.Matches(msg, m.cfg.KeyMap.SelectItem) && len(m.history.Items) > 0:
+			return m, m.Enter(&ItemSelectionMode{})
+
+		case key.Matches(msg, m.cfg.KeyMap.OpenPager):
+			if item, found := lastItemMatching(m.history.Items, hasOutput); found {
+				return m, m.Enter(&PagerMode{ItemID: item.ID})
+			}
+
+		case key.Matches(msg, m.cfg.KeyMap.CopyLastOutput):
+			if item, found := lastItemMatching(m.history.Items, hasOutput); found {
+				return m, m.copyToClipboard(item.Output)
+			}
+
+		case key.Matches(msg, m.cfg.KeyMap.CopyLastError):
+			if item, found := lastItemMatching(m.history.Items, hasError); found {
+				return m, m.copyToClipboard(errorText(m.cfg, item.Error))
+			}
+
+		case m.cfg.ViMode && key.Matches(msg, m.cfg.KeyMap.ViNormal):
+			return m.leaveViInsert()
+
 		case key.Matches(msg, m.cfg.KeyMap.Cancel):
 			if m.input.Value() != "" {
 				m.input.SetValue("")
@@ -116,7 +150,28 @@ func (c *CommandEntryMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
 }
 
 func (c *CommandEntryMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	editing := []key.Binding{keyMap.WordBackward, keyMap.WordForward, keyMap.KillWordBackward, keyMap.KillToEnd, keyMap.Yank, keyMap.YankPop, keyMap.Undo}
+	if m.cfg.ViMode {
+		editing = append([]key.Binding{keyMap.ViNormal}, editing...)
+	}
+
 	return [][]key.Binding{
 		c.ShortHelp(m, keyMap),
+		{keyMap.PageUp, keyMap.PageDown, keyMap.OpenPager, keyMap.SelectItem},
+		{keyMap.CopyLastOutput, keyMap.CopyLastError},
+		editing,
 	}
 }
+
+// lastItemMatching returns the most recent history item which matches the filter
+func lastItemMatching(items []history.Item, filter func(item history.Item) bool) (history.Item, bool) {
+	for i := len(items) - 1; i >= 0; i-- {
+		if filter(items[i]) {
+			return items[i], true
+		}
+	}
+	return history.Item{}, false
+}
+
+func hasOutput(item history.Item) bool { return item.Output != "" }
+func hasError(item history.Item) bool  { return item.Error != nil }
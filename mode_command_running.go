@@ -2,22 +2,41 @@ package shell
 
 import (
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// CommandRunningMode is the mode the shell is in while a command is running
+//
+// Lines the user types while in this mode are sent to the input of the
+// command, which it can read from cmd.InOrStdin().
 type CommandRunningMode struct{ KeepInputContent bool }
 
 var _ Mode = (*CommandRunningMode)(nil)
 
 func (c *CommandRunningMode) Enter(m Model) (Model, tea.Cmd) {
+	m.input.Prompt = ""
+	m.input.Placeholder = ""
+	m.input.SetValue("")
+	m.input.Focus()
+
 	return m, nil
 }
 
 func (c *CommandRunningMode) Leave(m Model) (Model, tea.Cmd) {
+	m.input.Blur()
+	m.input.SetValue("")
+
 	return m, nil
 }
 
 func (c *CommandRunningMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	// Mask what the user is typing if the command is reading sensitive input
+	m.input.EchoMode = textinput.EchoNormal
+	if m.currentCmdInput != nil && m.currentCmdInput.Masked() {
+		m.input.EchoMode = textinput.EchoPassword
+	}
+
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
 		switch {
@@ -29,6 +48,15 @@ func (c *CommandRunningMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 			} else {
 				return m, m.Shutdown
 			}
+
+		case key.Matches(msg, m.cfg.KeyMap.ExecuteCommand) && m.currentCmdInput != nil:
+			m.currentCmdInput.WriteLine(m.input.Value())
+			m.input.SetValue("")
+			return m, nil
+
+		case key.Matches(msg, m.cfg.KeyMap.EndOfInput) && m.currentCmdInput != nil:
+			m.currentCmdInput.CloseWrite()
+			return m, nil
 		}
 	}
 
@@ -48,8 +76,15 @@ func (c *CommandRunningMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
 		cancel.SetHelp(cancel.Help().Key, "quit")
 	}
 
+	if m.currentCmdInput == nil {
+		return []key.Binding{cancel}
+	}
+
+	send := keyMap.ExecuteCommand
+	send.SetHelp(send.Help().Key, "send input")
+
 	return []key.Binding{
-		cancel,
+		send, keyMap.EndOfInput, cancel,
 	}
 }
 
@@ -8,6 +8,10 @@ import (
 
 type HistoryLookbackMode struct {
 	TriggerMsg tea.Msg
+
+	// ViNormal is set when looking back through the history from the vi normal mode,
+	// where k and j also move through the history and which is returned to afterwards
+	ViNormal bool
 }
 
 var _ Mode = (*HistoryLookbackMode)(nil)
@@ -41,7 +45,7 @@ func (h *HistoryLookbackMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 				m.ExecuteCommand(historyItem),
 			)
 
-		case key.Matches(msg, m.cfg.KeyMap.Up):
+		case key.Matches(msg, m.cfg.KeyMap.Up) || h.ViNormal && (viKey(msg) == 'k' || viKey(msg) == '-'):
 			if len(m.history.Items) == 0 {
 				return m, nil
 			}
@@ -65,7 +69,7 @@ func (h *HistoryLookbackMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 
 			return m, nil
 
-		case key.Matches(msg, m.cfg.KeyMap.Down):
+		case key.Matches(msg, m.cfg.KeyMap.Down) || h.ViNormal && (viKey(msg) == 'j' || viKey(msg) == '+'):
 			if len(m.history.Items) == 0 {
 				return m, nil
 			}
@@ -85,22 +89,25 @@ func (h *HistoryLookbackMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 			}
 
 			if m.lookBack <= 0 {
-				return m, m.Enter(&CommandEntryMode{})
+				return m, m.Enter(viEntryMode(h.ViNormal, false))
 			} else {
 				m.input.SetValue(possibleLine)
 			}
 
 			return m, nil
 
+		case m.cfg.ViMode && key.Matches(msg, m.cfg.KeyMap.ViNormal):
+			return m, m.Enter(&ViNormalMode{KeepInputContent: true})
+
 		case key.Matches(msg, m.cfg.KeyMap.Cancel):
-			return m, m.Enter(&CommandEntryMode{})
+			return m, m.Enter(viEntryMode(h.ViNormal, false))
 
 		default:
 			if msg.Type == tea.KeyRight || msg.Type == tea.KeyTab {
-				return m, m.Enter(&CommandEntryMode{KeepInputContent: true})
+				return m, m.Enter(viEntryMode(h.ViNormal, true))
 			} else {
 				return m, tea.Sequence(
-					m.Enter(&CommandEntryMode{KeepInputContent: true}),
+					m.Enter(viEntryMode(h.ViNormal, true)),
 					func() tea.Msg { return msg },
 				)
 			}
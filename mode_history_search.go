@@ -6,7 +6,10 @@ import (
 	tea "github.com/charmbracelet/bubbletea"
 )
 
-type HistorySearchMode struct{}
+type HistorySearchMode struct {
+	// ViNormal is set when the search was started from the vi normal mode, which is returned to afterwards
+	ViNormal bool
+}
 
 var _ Mode = (*HistorySearchMode)(nil)
 
@@ -51,8 +54,12 @@ func (h *HistorySearchMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 			m.searchInput.Prompt = m.searchInputPrompt(found)
 			return m.updateSearchResult(foundIdx), nil
 
+		case m.cfg.ViMode && key.Matches(msg, m.cfg.KeyMap.ViNormal):
+			m.input.CursorEnd()
+			return m, m.Enter(&ViNormalMode{KeepInputContent: true})
+
 		case key.Matches(msg, m.cfg.KeyMap.Cancel):
-			return m, m.Enter(&CommandEntryMode{})
+			return m, m.Enter(viEntryMode(h.ViNormal, false))
 
 		case msg.Type == tea.KeyLeft || msg.Type == tea.KeyRight || msg.Type == tea.KeyTab:
 			line := m.history.Lookback(m.lookBack).Line
@@ -60,11 +67,11 @@ func (h *HistorySearchMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
 
 			if msg.Type == tea.KeyLeft {
 				return m, tea.Sequence(
-					m.Enter(&CommandEntryMode{KeepInputContent: true}),
+					m.Enter(viEntryMode(h.ViNormal, true)),
 					func() tea.Msg { return msg },
 				)
 			} else {
-				return m, m.Enter(&CommandEntryMode{KeepInputContent: true})
+				return m, m.Enter(viEntryMode(h.ViNormal, true))
 			}
 
 		default:
@@ -0,0 +1,184 @@
+package shell
+
+import (
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/rs/xid"
+)
+
+// ItemSelectionMode lets the user move a cursor over the items in the history, so they can
+// fold, run again, edit, copy or delete them
+type ItemSelectionMode struct {
+	message string // A message shown below the history, such as after copying an item
+}
+
+var _ Mode = (*ItemSelectionMode)(nil)
+
+func (s *ItemSelectionMode) Enter(m Model) (Model, tea.Cmd) {
+	// Items which have already been printed by an inline shell can't be selected
+	if m.cfg.InlineShell {
+		return m, m.Enter(&CommandEntryMode{})
+	}
+
+	idx := s.nextSelectable(m, len(m.history.Items), -1)
+	if idx < 0 {
+		return m, m.Enter(&CommandEntryMode{})
+	}
+
+	m.history.Select(m.history.Items[idx].ID)
+	return m, nil
+}
+
+func (s *ItemSelectionMode) Leave(m Model) (Model, tea.Cmd) {
+	m.history.ClearSelection()
+	return m, nil
+}
+
+func (s *ItemSelectionMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	item, found := m.history.SelectedItem()
+	if !found {
+		return m, m.Enter(&CommandEntryMode{})
+	}
+	idx := s.indexOf(m, item.ID)
+	s.message = ""
+
+	switch {
+	case key.Matches(keyMsg, m.cfg.KeyMap.Up):
+		if prev := s.nextSelectable(m, idx, -1); prev >= 0 {
+			m.history.Select(m.history.Items[prev].ID)
+		}
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.Down):
+		next := s.nextSelectable(m, idx, 1)
+		if next < 0 {
+			// Moving past the most recent item returns to the input
+			m.history.ScrollToBottom()
+			return m, m.Enter(&CommandEntryMode{})
+		}
+		m.history.Select(m.history.Items[next].ID)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.ToggleFold):
+		m.history.ToggleFolded(item.ID)
+		m.history.Select(item.ID)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.RerunItem) && isCommandItem(item):
+		historyItem := history.NewItem(m.input.Prompt, item.Line, history.RunningStatus)
+		m.history.ScrollToBottom()
+
+		return m, tea.Sequence(
+			m.Enter(&CommandRunningMode{}),
+			m.history.AppendItem(historyItem),
+			m.ExecuteCommand(historyItem),
+		)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.EditItem) && isCommandItem(item):
+		m.input.SetValue(item.Line)
+		m.input.CursorEnd()
+		m.history.ScrollToBottom()
+
+		return m, m.Enter(&CommandEntryMode{KeepInputContent: true})
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.CopyItem) && isCommandItem(item):
+		s.message = "copied the command to the clipboard"
+		return m, m.copyToClipboard(item.Line)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.CopyItemOutput) && hasOutput(item):
+		s.message = "copied the output to the clipboard"
+		return m, m.copyToClipboard(item.Output)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.CopyItemError) && hasError(item):
+		s.message = "copied the error to the clipboard"
+		return m, m.copyToClipboard(errorText(m.cfg, item.Error))
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.DeleteItem) && m.history.IsRunning(item.ID):
+		s.message = "unable to delete a command while it is running"
+		return m, nil
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.DeleteItem):
+		// Select the item before the deleted one, or after it if it was the first
+		next := s.nextSelectable(m, idx, -1)
+		if next < 0 {
+			next = s.nextSelectable(m, s.lastSubCommand(m, idx), 1)
+		}
+
+		if next < 0 {
+			return m, tea.Sequence(m.history.RemoveItem(item.ID), m.Enter(&CommandEntryMode{}))
+		}
+		m.history.Select(m.history.Items[next].ID)
+		return m, m.history.RemoveItem(item.ID)
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.OpenPager) && hasOutput(item):
+		return m, m.Enter(&PagerMode{ItemID: item.ID})
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.Cancel, m.cfg.KeyMap.SelectItem):
+		return m, m.Enter(&CommandEntryMode{})
+	}
+
+	return m, nil
+}
+
+// indexOf returns the index of the item with the given ID in the history, or -1 if it's not found
+func (s *ItemSelectionMode) indexOf(m Model, id xid.ID) int {
+	for i := len(m.history.Items) - 1; i >= 0; i-- {
+		if m.history.Items[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextSelectable returns the index of the next item which can be selected from the start index
+// moving by delta, or -1 if there are no more items which can be selected
+func (s *ItemSelectionMode) nextSelectable(m Model, start int, delta int) int {
+	for i := start + delta; i >= 0 && i < len(m.history.Items); i += delta {
+		if m.history.Items[i].ItemType != history.HistoryRestored {
+			return i
+		}
+	}
+	return -1
+}
+
+// lastSubCommand returns the index of the last sub command run from the item at idx,
+// as they are deleted along with it, or idx if it has none
+func (s *ItemSelectionMode) lastSubCommand(m Model, idx int) int {
+	if m.history.Items[idx].ItemType == history.SubCommand {
+		return idx
+	}
+
+	for idx+1 < len(m.history.Items) && m.history.Items[idx+1].ItemType == history.SubCommand {
+		idx++
+	}
+	return idx
+}
+
+// isCommandItem returns true if the line of the item is a command which can be run
+func isCommandItem(item history.Item) bool {
+	return item.ItemType == history.Command || item.ItemType == history.SubCommand
+}
+
+func (s *ItemSelectionMode) AdditionalView(m Model) string {
+	if s.message == "" {
+		return ""
+	}
+	return m.cfg.Styles.HistoricLine.Render(s.message)
+}
+
+func (s *ItemSelectionMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	return []key.Binding{
+		keyMap.Up, keyMap.Down, keyMap.ToggleFold, keyMap.RerunItem, keyMap.EditItem, keyMap.Cancel,
+	}
+}
+
+func (s *ItemSelectionMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		{keyMap.Up, keyMap.Down, keyMap.ToggleFold, keyMap.OpenPager},
+		{keyMap.RerunItem, keyMap.EditItem, keyMap.DeleteItem, keyMap.Cancel},
+		{keyMap.CopyItem, keyMap.CopyItemOutput, keyMap.CopyItemError},
+	}
+}
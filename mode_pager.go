@@ -0,0 +1,273 @@
+package shell
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/rs/xid"
+)
+
+// PagerAnnotation can be added to the annotations of a command with any value to have
+// its output opened in the pager once it finishes, if the output is taller than the window.
+//
+//	cmd.Annotations = map[string]string{shell.PagerAnnotation: "true"}
+const PagerAnnotation = "bubble-shell:pager"
+
+// sgrSequence matches the SGR escape sequences used to style command output
+var sgrSequence = regexp.MustCompile("\x1b\\[[0-9;]*m")
+
+// PagerMode shows the output of a history item on the whole screen, so output taller
+// than the window can be scrolled through and searched
+type PagerMode struct {
+	ItemID xid.ID // The ID of the history item to show
+
+	title     string
+	lines     []string // The lines of the output
+	wrapped   []string // The lines wrapped to the width of the window
+	plain     []string // The wrapped lines without any styling, used for searching
+	wrapWidth int
+
+	offset  int    // The index of the first wrapped line shown
+	match   int    // The index of the wrapped line of the current search match, or -1 if there is none
+	message string // A message shown in the status line, such as when a search has no matches
+
+	searching   bool
+	searchInput textinput.Model
+	search      string
+}
+
+var (
+	_ Mode           = (*PagerMode)(nil)
+	_ fullScreenMode = (*PagerMode)(nil)
+)
+
+func (p *PagerMode) Enter(m Model) (Model, tea.Cmd) {
+	p.match = -1
+	p.offset = 0
+	p.wrapWidth = 0
+
+	for _, item := range m.history.Items {
+		if item.ID == p.ItemID {
+			p.title = item.Line
+			p.lines = strings.Split(item.Output, "\n")
+		}
+	}
+
+	p.searchInput = textinput.New()
+	p.searchInput.Prompt = "/"
+	p.searchInput.TextStyle = m.cfg.Styles.Search
+	p.searchInput.PromptStyle = m.cfg.Styles.SearchPrompt
+	p.searchInput.Cursor.Style = m.cfg.Styles.Cursor
+
+	return m, nil
+}
+
+func (p *PagerMode) Leave(m Model) (Model, tea.Cmd) {
+	p.searchInput.Blur()
+	return m, nil
+}
+
+func (p *PagerMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	p.wrap(m.width)
+
+	if mouseMsg, ok := msg.(tea.MouseMsg); ok {
+		switch mouseMsg.Type {
+		case tea.MouseWheelUp:
+			p.scrollTo(m, p.offset-mouseWheelLines)
+		case tea.MouseWheelDown:
+			p.scrollTo(m, p.offset+mouseWheelLines)
+		}
+		return m, nil
+	}
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		if p.searching {
+			var cmd tea.Cmd
+			p.searchInput, cmd = p.searchInput.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	p.message = ""
+
+	if p.searching {
+		switch {
+		case key.Matches(keyMsg, m.cfg.KeyMap.ExecuteCommand):
+			p.searching = false
+			p.searchInput.Blur()
+			p.search = strings.ToLower(p.searchInput.Value())
+			p.findMatch(m, p.offset, 1)
+
+		case key.Matches(keyMsg, m.cfg.KeyMap.Cancel):
+			p.searching = false
+			p.searchInput.Blur()
+
+		default:
+			var cmd tea.Cmd
+			p.searchInput, cmd = p.searchInput.Update(msg)
+			return m, cmd
+		}
+		return m, nil
+	}
+
+	switch {
+	case key.Matches(keyMsg, m.cfg.KeyMap.PagerQuit, m.cfg.KeyMap.Cancel):
+		return m, m.Enter(&CommandEntryMode{})
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.Up):
+		p.scrollTo(m, p.offset-1)
+	case key.Matches(keyMsg, m.cfg.KeyMap.Down):
+		p.scrollTo(m, p.offset+1)
+	case key.Matches(keyMsg, m.cfg.KeyMap.PageUp):
+		p.scrollTo(m, p.offset-p.pageHeight(m))
+	case key.Matches(keyMsg, m.cfg.KeyMap.PageDown):
+		p.scrollTo(m, p.offset+p.pageHeight(m))
+	case key.Matches(keyMsg, m.cfg.KeyMap.Home):
+		p.scrollTo(m, 0)
+	case key.Matches(keyMsg, m.cfg.KeyMap.End):
+		p.scrollTo(m, len(p.wrapped))
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.PagerSearch):
+		p.searching = true
+		p.searchInput.SetValue("")
+		return m, p.searchInput.Focus()
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.PagerNextMatch):
+		p.findMatch(m, p.match+1, 1)
+	case key.Matches(keyMsg, m.cfg.KeyMap.PagerPreviousMatch):
+		start := p.match - 1
+		if p.match < 0 {
+			start = p.offset
+		}
+		p.findMatch(m, start, -1)
+	}
+
+	return m, nil
+}
+
+// wrap wraps the lines of the output to the given width, if they
+// have not already been wrapped to that width
+func (p *PagerMode) wrap(width int) {
+	if width == p.wrapWidth && p.wrapped != nil {
+		return
+	}
+	p.wrapWidth = width
+
+	style := lipgloss.NewStyle().Width(width)
+	p.wrapped = p.wrapped[:0]
+	p.plain = p.plain[:0]
+	for _, line := range p.lines {
+		if width > 0 {
+			line = style.Render(line)
+		}
+
+		for _, wrapped := range strings.Split(line, "\n") {
+			p.wrapped = append(p.wrapped, wrapped)
+			p.plain = append(p.plain, strings.TrimRight(sgrSequence.ReplaceAllString(wrapped, ""), " "))
+		}
+	}
+
+	// The match is no longer at the same index
+	p.match = -1
+}
+
+// pageHeight returns the number of lines of output shown at once
+func (p *PagerMode) pageHeight(m Model) int {
+	if m.height <= 1 {
+		return 1
+	}
+	return m.height - 1 // one for the status line
+}
+
+// scrollTo scrolls to the given line, keeping a full page of output shown if possible
+func (p *PagerMode) scrollTo(m Model, offset int) {
+	maxOffset := len(p.wrapped) - p.pageHeight(m)
+	if offset > maxOffset {
+		offset = maxOffset
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	p.offset = offset
+}
+
+// findMatch finds the next line matching the search from the start line moving by delta,
+// wrapping around the output if needed, and scrolls the match into view
+func (p *PagerMode) findMatch(m Model, start int, delta int) {
+	if p.search == "" {
+		return
+	}
+
+	for i := 0; i < len(p.plain); i++ {
+		idx := ((start+i*delta)%len(p.plain) + len(p.plain)) % len(p.plain)
+
+		if strings.Contains(strings.ToLower(p.plain[idx]), p.search) {
+			p.match = idx
+			if idx < p.offset || idx >= p.offset+p.pageHeight(m) {
+				p.scrollTo(m, idx)
+			}
+			return
+		}
+	}
+
+	p.match = -1
+	p.message = fmt.Sprintf("%q not found", p.search)
+}
+
+func (p *PagerMode) AdditionalView(m Model) string {
+	p.wrap(m.width)
+
+	height := p.pageHeight(m)
+	lines := make([]string, 0, height+1)
+	for i := p.offset; i < len(p.wrapped) && len(lines) < height; i++ {
+		if i == p.match {
+			lines = append(lines, m.cfg.Styles.PagerMatch.Render(p.plain[i]))
+		} else {
+			lines = append(lines, p.wrapped[i])
+		}
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	// Render the status line
+	if p.searching {
+		lines = append(lines, p.searchInput.View())
+	} else {
+		last := p.offset + height
+		if last > len(p.wrapped) {
+			last = len(p.wrapped)
+		}
+
+		status := fmt.Sprintf(" %s  lines %d-%d of %d", p.title, p.offset+1, last, len(p.wrapped))
+		if p.message != "" {
+			status += "  " + p.message
+		}
+		lines = append(lines, m.cfg.Styles.PagerStatus.Copy().Width(m.width).MaxHeight(1).Render(status))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (p *PagerMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	return []key.Binding{
+		keyMap.Up, keyMap.Down, keyMap.PageUp, keyMap.PageDown,
+		keyMap.PagerSearch, keyMap.PagerNextMatch, keyMap.PagerQuit,
+	}
+}
+
+func (p *PagerMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		{keyMap.Up, keyMap.Down, keyMap.PageUp, keyMap.PageDown, keyMap.Home, keyMap.End},
+		{keyMap.PagerSearch, keyMap.PagerNextMatch, keyMap.PagerPreviousMatch, keyMap.PagerQuit},
+	}
+}
+
+func (p *PagerMode) fullScreen() {}
@@ -0,0 +1,40 @@
+package shell
+
+import (
+	"testing"
+)
+
+func TestPagerFindMatch(t *testing.T) {
+	m := Model{height: 4, width: 40}
+	p := &PagerMode{lines: []string{"alpha", "\x1b[1mBeta\x1b[0m", "gamma", "beta two", "delta", "epsilon"}}
+	p.wrap(m.width)
+	p.search = "beta"
+
+	tests := []struct {
+		start    int
+		delta    int
+		expected int
+	}{
+		{start: 0, delta: 1, expected: 1},
+		{start: 2, delta: 1, expected: 3},
+		{start: 4, delta: 1, expected: 1}, // wraps around to the start
+		{start: 2, delta: -1, expected: 1},
+		{start: 0, delta: -1, expected: 3}, // wraps around to the end
+	}
+
+	for _, test := range tests {
+		p.findMatch(m, test.start, test.delta)
+		if p.match != test.expected {
+			t.Errorf("findMatch(%d, %d): expected match on line %d but got %d", test.start, test.delta, test.expected, p.match)
+		}
+		if p.match < p.offset || p.match >= p.offset+p.pageHeight(m) {
+			t.Errorf("findMatch(%d, %d): expected line %d to be shown but the offset is %d", test.start, test.delta, p.match, p.offset)
+		}
+	}
+
+	p.search = "missing"
+	p.findMatch(m, 0, 1)
+	if p.match != -1 || p.message == "" {
+		t.Errorf("expected no match and a message but got match %d and message %q", p.match, p.message)
+	}
+}
@@ -0,0 +1,166 @@
+package shell
+
+import (
+	"context"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/pkg/interact"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/cockroachdb/errors"
+)
+
+// questionAsker passes the questions asked by the running command to the shell,
+// implementing [interact.Asker]
+type questionAsker struct {
+	requests chan *interact.Request
+}
+
+var _ interact.Asker = (*questionAsker)(nil)
+
+func newQuestionAsker() *questionAsker {
+	return &questionAsker{requests: make(chan *interact.Request)}
+}
+
+func (q *questionAsker) Ask(ctx context.Context, req *interact.Request) error {
+	select {
+	case q.requests <- req:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// waitForQuestion is a [tea.Cmd] which waits for the next
+// question asked by a command
+func (m Model) waitForQuestion() tea.Msg {
+	return askQuestionMsg{
+		id:      m.id,
+		request: <-m.asker.requests,
+	}
+}
+
+// QuestionMode is the mode the shell is in while the running command is
+// waiting for the user to answer a question asked with the [interact] package
+type QuestionMode struct {
+	Request *interact.Request
+
+	input    textinput.Model
+	selected int
+}
+
+var _ Mode = (*QuestionMode)(nil)
+
+func (q *QuestionMode) Enter(m Model) (Model, tea.Cmd) {
+	q.input = textinput.New()
+	q.input.Prompt = ""
+	q.input.TextStyle = m.cfg.Styles.Command
+	q.input.Cursor.Style = m.cfg.Styles.Cursor
+	q.input.Width = m.width
+	if q.Request.Kind == interact.PasswordKind {
+		q.input.EchoMode = textinput.EchoPassword
+	}
+
+	return m, q.input.Focus()
+}
+
+func (q *QuestionMode) Leave(m Model) (Model, tea.Cmd) {
+	q.input.Blur()
+	return m, nil
+}
+
+func (q *QuestionMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	if keyMsg, ok := msg.(tea.KeyMsg); ok {
+		switch {
+		case key.Matches(keyMsg, m.cfg.KeyMap.Cancel):
+			q.Request.Cancel()
+			return m, m.Enter(&CommandRunningMode{})
+
+		case key.Matches(keyMsg, m.cfg.KeyMap.ExecuteCommand):
+			// Confirmations default to no
+			return q.answer(m, false)
+		}
+
+		switch q.Request.Kind {
+		case interact.ConfirmKind:
+			switch strings.ToLower(keyMsg.String()) {
+			case "y":
+				return q.answer(m, true)
+			case "n":
+				return q.answer(m, false)
+			}
+			return m, nil
+
+		case interact.SelectKind:
+			switch {
+			case key.Matches(keyMsg, m.cfg.KeyMap.Up, m.cfg.KeyMap.PreviousAutoComplete):
+				q.selected = (q.selected + len(q.Request.Options) - 1) % len(q.Request.Options)
+			case key.Matches(keyMsg, m.cfg.KeyMap.Down, m.cfg.KeyMap.AutoComplete):
+				q.selected = (q.selected + 1) % len(q.Request.Options)
+			}
+			return m, nil
+		}
+	}
+
+	var cmd tea.Cmd
+	q.input, cmd = q.input.Update(msg)
+	return m, cmd
+}
+
+// answer responds to the request with the answer the user has given,
+// and returns to the command running mode
+func (q *QuestionMode) answer(m Model, confirmed bool) (Model, tea.Cmd) {
+	answer := interact.Answer{Confirmed: confirmed, Text: q.input.Value()}
+	if q.Request.Kind == interact.SelectKind {
+		answer.Index = q.selected
+		answer.Text = q.Request.Options[q.selected]
+	}
+
+	q.Request.Respond(answer)
+	return m, m.Enter(&CommandRunningMode{})
+}
+
+func (q *QuestionMode) AdditionalView(m Model) string {
+	label := m.cfg.Styles.QuestionLabel.Render(q.Request.Label)
+
+	switch q.Request.Kind {
+	case interact.ConfirmKind:
+		return label + " " + m.cfg.Styles.QuestionOption.Render("[y/N]")
+
+	case interact.SelectKind:
+		lines := make([]string, 0, len(q.Request.Options)+1)
+		lines = append(lines, label)
+		for i, option := range q.Request.Options {
+			if i == q.selected {
+				lines = append(lines, m.cfg.Styles.QuestionSelectedOption.Render("> "+option))
+			} else {
+				lines = append(lines, m.cfg.Styles.QuestionOption.Render("  "+option))
+			}
+		}
+		return lipgloss.JoinVertical(lipgloss.Left, lines...)
+
+	default:
+		return label + " " + q.input.View()
+	}
+}
+
+func (q *QuestionMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	answer := keyMap.ExecuteCommand
+	answer.SetHelp(answer.Help().Key, "answer")
+
+	cancel := keyMap.Cancel
+	cancel.SetHelp(cancel.Help().Key, "cancel question")
+
+	if q.Request.Kind == interact.SelectKind {
+		return []key.Binding{keyMap.Up, keyMap.Down, answer, cancel}
+	}
+	return []key.Binding{answer, cancel}
+}
+
+func (q *QuestionMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		q.ShortHelp(m, keyMap),
+	}
+}
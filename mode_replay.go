@@ -0,0 +1,246 @@
+package shell
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/internal/termemu"
+	"github.com/DomBlack/bubble-shell/pkg/modelid"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	// defaultReplayIdleLimit is the longest pause between events during a replay,
+	// if the recording doesn't set its own limit
+	defaultReplayIdleLimit = 2 * time.Second
+
+	minReplaySpeed = 0.25
+	maxReplaySpeed = 16
+)
+
+// ReplayMode plays back a recording of a terminal session, such as one made with
+// [WithRecording], on the whole screen. It is entered by the `replay` command,
+// which waits for the replay to be stopped.
+type ReplayMode struct {
+	Title string  // The title shown in the status line
+	Speed float64 // How many times faster than it was recorded the recording is played
+
+	recording *asciicast.Recording
+	done      chan struct{} // Closed once the replay has been stopped
+	stopped   bool          // Set once the replay has been stopped, after which keys are ignored
+
+	screen   termemu.Screen
+	next     int           // The index of the next event to play
+	position time.Duration // How far through the recording the replay is
+	paused   bool
+	tick     int // Incremented each time the next event is scheduled, so earlier ticks are ignored
+}
+
+var (
+	_ Mode           = (*ReplayMode)(nil)
+	_ fullScreenMode = (*ReplayMode)(nil)
+)
+
+// replayTickMsg is sent when it's time to play the next event of the replay
+type replayTickMsg struct {
+	id   modelid.ID
+	mode *ReplayMode
+	tick int
+}
+
+func (msg replayTickMsg) ForModelID() modelid.ID {
+	return msg.id
+}
+
+func (r *ReplayMode) Enter(m Model) (Model, tea.Cmd) {
+	if r.Speed <= 0 {
+		r.Speed = 1
+	}
+	r.restart()
+
+	return m, r.scheduleNext(m)
+}
+
+func (r *ReplayMode) Leave(m Model) (Model, tea.Cmd) {
+	// Stop any scheduled events being played
+	r.tick++
+	return m, nil
+}
+
+func (r *ReplayMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	if r.stopped {
+		return m, nil
+	}
+
+	switch msg := msg.(type) {
+	case replayTickMsg:
+		if !m.id.Matches(msg) || msg.mode != r || msg.tick != r.tick {
+			return m, nil
+		}
+
+		// Play all the events which happened at the same time together
+		at := r.recording.Events[r.next].Time
+		for r.next < len(r.recording.Events) && r.recording.Events[r.next].Time == at {
+			r.play(r.recording.Events[r.next])
+			r.next++
+		}
+		return m, r.scheduleNext(m)
+
+	case tea.KeyMsg:
+		switch {
+		case key.Matches(msg, m.cfg.KeyMap.ReplayQuit, m.cfg.KeyMap.Cancel):
+			r.stopped = true
+			if r.done != nil {
+				close(r.done)
+			}
+
+			// The mode is left straight away, rather than with [Model.Enter], so the shell can't return
+			// to command entry once the replay command finishes before it has left the replay
+			return m.switchMode(&CommandRunningMode{})
+
+		case key.Matches(msg, m.cfg.KeyMap.ReplayPause):
+			if r.finished() {
+				r.restart()
+			} else {
+				r.paused = !r.paused
+			}
+			return m, r.scheduleNext(m)
+
+		case key.Matches(msg, m.cfg.KeyMap.ReplayFaster):
+			r.Speed *= 2
+			if r.Speed > maxReplaySpeed {
+				r.Speed = maxReplaySpeed
+			}
+			return m, r.scheduleNext(m)
+
+		case key.Matches(msg, m.cfg.KeyMap.ReplaySlower):
+			r.Speed /= 2
+			if r.Speed < minReplaySpeed {
+				r.Speed = minReplaySpeed
+			}
+			return m, r.scheduleNext(m)
+
+		case key.Matches(msg, m.cfg.KeyMap.Home):
+			r.restart()
+			return m, r.scheduleNext(m)
+
+		case key.Matches(msg, m.cfg.KeyMap.End):
+			for !r.finished() {
+				r.play(r.recording.Events[r.next])
+				r.next++
+			}
+			return m, nil
+		}
+	}
+
+	return m, nil
+}
+
+// restart resets the replay to the start of the recording
+func (r *ReplayMode) restart() {
+	r.screen = termemu.Screen{}
+	r.next = 0
+	r.position = 0
+	r.paused = false
+}
+
+// finished reports whether every event of the recording has been played
+func (r *ReplayMode) finished() bool {
+	return r.next >= len(r.recording.Events)
+}
+
+// play applies the event to the screen
+func (r *ReplayMode) play(event asciicast.Event) {
+	r.position = event.Elapsed()
+	if event.Type == asciicast.OutputEvent {
+		_, _ = r.screen.Write([]byte(event.Data))
+	}
+}
+
+// scheduleNext returns a [tea.Cmd] which waits until the next event should be played,
+// at the current speed of the replay, replacing any event which was already scheduled
+func (r *ReplayMode) scheduleNext(m Model) tea.Cmd {
+	r.tick++
+	if r.paused || r.finished() {
+		return nil
+	}
+
+	idleLimit := defaultReplayIdleLimit
+	if limit := r.recording.Header.IdleTimeLimit; limit > 0 {
+		idleLimit = time.Duration(limit * float64(time.Second))
+	}
+
+	delay := r.recording.Events[r.next].Elapsed() - r.position
+	if delay > idleLimit {
+		delay = idleLimit
+	}
+
+	msg := replayTickMsg{id: m.id, mode: r, tick: r.tick}
+	return tea.Tick(time.Duration(float64(delay)/r.Speed), func(time.Time) tea.Msg {
+		return msg
+	})
+}
+
+func (r *ReplayMode) AdditionalView(m Model) string {
+	height := m.height - 1
+	if height < 1 {
+		height = 1
+	}
+
+	// The recording redraws the whole screen it was recorded on, so like a terminal of that
+	// size would, if the screen is smaller only the bottom of the recording is shown
+	lines := strings.Split(r.screen.String(), "\n")
+	for len(lines) < r.recording.Header.Height {
+		lines = append(lines, "")
+	}
+	if len(lines) > height {
+		lines = lines[len(lines)-height:]
+	}
+	for len(lines) < height {
+		lines = append(lines, "")
+	}
+
+	crop := lipgloss.NewStyle().MaxWidth(m.width)
+	for i, line := range lines {
+		lines[i] = crop.Render(line)
+	}
+
+	status := fmt.Sprintf(" %s  %s / %s  %sx",
+		r.Title, formatReplayTime(r.position), formatReplayTime(r.recording.Duration()),
+		strconv.FormatFloat(r.Speed, 'g', -1, 64),
+	)
+	switch {
+	case r.finished():
+		status += "  finished"
+	case r.paused:
+		status += "  paused"
+	}
+	lines = append(lines, m.cfg.Styles.ReplayStatus.Copy().Width(m.width).MaxHeight(1).Render(status))
+
+	return strings.Join(lines, "\n")
+}
+
+// formatReplayTime formats how far through a recording the replay is as minutes and seconds
+func formatReplayTime(d time.Duration) string {
+	seconds := int(d / time.Second)
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+func (r *ReplayMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	return []key.Binding{
+		keyMap.ReplayPause, keyMap.ReplayFaster, keyMap.ReplaySlower, keyMap.ReplayQuit,
+	}
+}
+
+func (r *ReplayMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		{keyMap.ReplayPause, keyMap.ReplayFaster, keyMap.ReplaySlower, keyMap.ReplayQuit},
+	}
+}
+
+func (r *ReplayMode) fullScreen() {}
@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	cfg := config.Default()
+	cfg.Styles = styles.Plain
+	m := Model{cfg: cfg, height: 4, width: 40}
+
+	// Record a few frames, including one which is repeated and one taller than the screen
+	path := filepath.Join(t.TempDir(), "session.cast")
+	r := newRecording(path)
+	if cmd := r.resize(m, 40, 3); cmd != nil {
+		t.Fatalf("unexpected error starting the recording: %v", cmd())
+	}
+	r.frame("first\n> ")
+	r.frame("first\n> ")
+	r.frame("first\nsecond line\nthird\n> ls")
+	r.close()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unable to open the recording: %v", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	recording, err := asciicast.Read(file)
+	if err != nil {
+		t.Fatalf("unable to read the recording: %v", err)
+	}
+	if recording.Header.Width != 40 || recording.Header.Height != 3 {
+		t.Errorf("expected the recording to be 40x3 but got %dx%d", recording.Header.Width, recording.Header.Height)
+	}
+	if len(recording.Events) != 3 {
+		t.Fatalf("expected the repeated frame not to be recorded, giving 3 events but got %d", len(recording.Events))
+	}
+
+	// Replay the recording
+	p := &ReplayMode{Title: "demo", recording: recording}
+	m, _ = p.Enter(m)
+
+	m, _ = p.Update(m, replayTickMsg{id: m.id, mode: p, tick: p.tick})
+	m, _ = p.Update(m, replayTickMsg{id: m.id, mode: p, tick: p.tick})
+	if view := p.AdditionalView(m); !strings.HasPrefix(view, "first\n>\n") {
+		t.Errorf("expected the first frame to be shown but got:\n%s", view)
+	}
+
+	// Ticks scheduled before the speed was changed are ignored
+	oldTick := p.tick
+	m, _ = p.Update(m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("+")})
+	if p.Speed != 2 {
+		t.Errorf("expected the speed to be doubled but got %v", p.Speed)
+	}
+	m, _ = p.Update(m, replayTickMsg{id: m.id, mode: p, tick: oldTick})
+	if p.next != 2 {
+		t.Errorf("expected the old tick to be ignored but %d events have been played", p.next)
+	}
+
+	m, _ = p.Update(m, tea.KeyMsg{Type: tea.KeyEnd})
+	view := p.AdditionalView(m)
+	if !strings.HasPrefix(view, "second line\nthird\n> ls\n") || !strings.Contains(view, "demo") || !strings.Contains(view, "finished") {
+		t.Errorf("expected the bottom of the last frame and the status line to be shown but got:\n%s", view)
+	}
+}
+
+func TestReplayQuitTwice(t *testing.T) {
+	cfg := config.Default()
+	cfg.Styles = styles.Plain
+	m := newModel(cfg, &cobra.Command{})
+
+	p := &ReplayMode{recording: &asciicast.Recording{}, done: make(chan struct{})}
+	m.mode = p
+	m, _ = p.Enter(m)
+
+	// A second quit can be queued before the first has been handled
+	quit := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")}
+	m, _ = p.Update(m, quit)
+	m, _ = p.Update(m, quit)
+
+	select {
+	case <-p.done:
+	default:
+		t.Errorf("expected the replay to be stopped")
+	}
+	if _, ok := m.mode.(*CommandRunningMode); !ok {
+		t.Errorf("expected the replay to be left straight away but the mode is %T", m.mode)
+	}
+}
@@ -0,0 +1,103 @@
+package shell
+
+import (
+	"reflect"
+
+	"github.com/DomBlack/bubble-shell/pkg/interact"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TakeoverMode is the mode the shell is in while the running command has handed
+// the screen over to its own [tea.Model] using [interact.RunModel]
+//
+// All messages, including key presses, are passed to the model until it quits.
+type TakeoverMode struct {
+	Request *interact.Request
+
+	model tea.Model
+}
+
+var (
+	_ Mode           = (*TakeoverMode)(nil)
+	_ fullScreenMode = (*TakeoverMode)(nil)
+)
+
+func (t *TakeoverMode) Enter(m Model) (Model, tea.Cmd) {
+	t.model = t.Request.Model
+	initCmd := t.model.Init()
+
+	var sizeCmd tea.Cmd
+	t.model, sizeCmd = t.model.Update(tea.WindowSizeMsg{Width: m.width, Height: m.height})
+
+	return m, tea.Batch(t.wrapCmd(m, initCmd), t.wrapCmd(m, sizeCmd))
+}
+
+func (t *TakeoverMode) Leave(m Model) (Model, tea.Cmd) {
+	return m, nil
+}
+
+func (t *TakeoverMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	if msg, ok := msg.(takeoverQuitMsg); ok {
+		if m.id.Matches(msg) && msg.request == t.Request {
+			t.Request.Respond(interact.Answer{Model: t.model})
+			return m, m.Enter(&CommandRunningMode{})
+		}
+		return m, nil
+	}
+
+	var cmd tea.Cmd
+	t.model, cmd = t.model.Update(msg)
+	return m, t.wrapCmd(m, cmd)
+}
+
+// wrapCmd wraps the command returned by the model, so if it quits
+// the model finishes rather than the whole shell quitting
+func (t *TakeoverMode) wrapCmd(m Model, cmd tea.Cmd) tea.Cmd {
+	if cmd == nil {
+		return nil
+	}
+
+	return func() tea.Msg {
+		msg := cmd()
+		if _, ok := msg.(tea.QuitMsg); ok {
+			return takeoverQuitMsg{id: m.id, request: t.Request}
+		}
+
+		// Batches and sequences are lists of commands which each need to be wrapped. The message
+		// from tea.Sequence is unexported, so the list is rebuilt with reflection to keep its type
+		if value := reflect.ValueOf(msg); value.Kind() == reflect.Slice && value.Type().Elem() == cmdType {
+			wrapped := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			for i := 0; i < value.Len(); i++ {
+				cmd, _ := value.Index(i).Interface().(tea.Cmd)
+				wrapped.Index(i).Set(reflect.ValueOf(t.wrapCmd(m, cmd)))
+			}
+			return wrapped.Interface()
+		}
+
+		return msg
+	}
+}
+
+// cmdType is the type of the commands within a [tea.BatchMsg] or the message from [tea.Sequence]
+var cmdType = reflect.TypeOf((*tea.Cmd)(nil)).Elem()
+
+func (t *TakeoverMode) AdditionalView(m Model) string {
+	return t.model.View()
+}
+
+func (t *TakeoverMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	if help, ok := t.model.(interface{ ShortHelp() []key.Binding }); ok {
+		return help.ShortHelp()
+	}
+	return nil
+}
+
+func (t *TakeoverMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	if help, ok := t.model.(interface{ FullHelp() [][]key.Binding }); ok {
+		return help.FullHelp()
+	}
+	return nil
+}
+
+func (t *TakeoverMode) fullScreen() {}
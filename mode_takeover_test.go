@@ -0,0 +1,85 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/pkg/interact"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+type pickedMsg struct{}
+
+// pickerModel picks when `q` is pressed, quitting once the pick has been made
+type pickerModel struct {
+	picked bool
+}
+
+func (p pickerModel) Init() tea.Cmd { return nil }
+
+func (p pickerModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "q" {
+			return p, tea.Sequence(func() tea.Msg { return pickedMsg{} }, tea.Quit)
+		}
+	case pickedMsg:
+		p.picked = true
+	}
+	return p, nil
+}
+
+func (p pickerModel) View() string {
+	if p.picked {
+		return "picked"
+	}
+	return "picking"
+}
+
+func TestTakeoverQuitWithSequence(t *testing.T) {
+	rootCmd := testRootCmd()
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "pick",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := interact.RunModel(cmd.Context(), cmd.OutOrStdout(), pickerModel{})
+			return err
+		},
+	})
+
+	takenOver := make(chan struct{})
+	finished := make(chan struct{})
+	var takenOverOnce, finishedOnce bool
+
+	m := runShell(t, New(rootCmd, WithNoHistory()),
+		func(m Model) {
+			if _, ok := m.mode.(*TakeoverMode); ok && !takenOverOnce {
+				takenOverOnce = true
+				close(takenOver)
+			}
+
+			for _, item := range m.history.Items {
+				if item.Line == "pick" && item.Status == history.SuccessStatus && !finishedOnce {
+					finishedOnce = true
+					close(finished)
+				}
+			}
+		},
+		func(p *tea.Program) {
+			enterLine(p, "pick")
+			if !wait(t, takenOver, "the model to take over the screen") {
+				return
+			}
+
+			p.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+			wait(t, finished, "the command to finish once the model quits, rather than the shell quitting")
+		},
+	)
+
+	for _, item := range m.history.Items {
+		if item.Line == "pick" && !strings.Contains(item.Output, "picked") {
+			t.Errorf("expected the final view of the model to be output but got %q", item.Output)
+		}
+	}
+}
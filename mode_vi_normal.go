@@ -0,0 +1,111 @@
+package shell
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ViNormalMode is the vi normal mode of the command input, which is used when the shell
+// is created with [WithViMode]. Key presses are vi commands which move around and edit the
+// input, with [CommandEntryMode] being the insert mode where the command is typed.
+type ViNormalMode struct {
+	KeepInputContent bool
+
+	pending viCommand // The command being typed
+}
+
+var _ Mode = (*ViNormalMode)(nil)
+
+func (v *ViNormalMode) Enter(m Model) (Model, tea.Cmd) {
+	if !v.KeepInputContent {
+		m.input.SetValue(m.lookBackPartial)
+	}
+
+	m.lookBackPartial = ""
+	m.input.Prompt = m.cfg.PromptFunc()
+	m.input.Placeholder = inputPlaceholder
+	m.input.EchoMode = textinput.EchoNormal
+	m.input.Focus()
+	m.clampViCursor()
+
+	return m, nil
+}
+
+func (v *ViNormalMode) Leave(m Model) (Model, tea.Cmd) {
+	v.pending = viCommand{}
+	m.lookBackPartial = m.input.Value()
+	m.input.Blur()
+	return m, nil
+}
+
+func (v *ViNormalMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	r := viKey(keyMsg)
+	if r == 0 {
+		// Any other key abandons a partly typed command
+		v.pending = viCommand{}
+	}
+	typing := v.pending != viCommand{}
+
+	switch {
+	case key.Matches(keyMsg, m.cfg.KeyMap.ViNormal):
+		// Escape abandons a partly typed command
+		v.pending = viCommand{}
+		return m, nil
+
+	case typing:
+		// The rest of the command, such as the motion for an operator
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.Up) || r == 'k' || r == '-':
+		if len(m.history.Items) > 0 {
+			return m, m.Enter(&HistoryLookbackMode{TriggerMsg: keyMsg, ViNormal: true})
+		}
+		return m, nil
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.SearchHistoryBackwards) || r == '/':
+		if len(m.history.Items) > 0 {
+			return m, m.Enter(&HistorySearchMode{ViNormal: true})
+		}
+		return m, nil
+
+	case key.Matches(keyMsg, m.cfg.KeyMap.Down, m.cfg.KeyMap.AutoComplete) || r == 'j' || r == '+':
+		// There is nothing below the input to move down to, and nothing to complete
+		return m, nil
+
+	case r == 0:
+		// Keys which aren't vi commands, such as to run the command, do the same as in insert mode
+		return (&CommandEntryMode{}).Update(m, msg)
+	}
+
+	if !v.pending.add(r, true) {
+		return m, nil
+	}
+
+	cmd := v.pending
+	v.pending = viCommand{}
+	return m.runViCommand(cmd, false)
+}
+
+func (v *ViNormalMode) AdditionalView(m Model) string {
+	return ""
+}
+
+func (v *ViNormalMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	return []key.Binding{
+		viInsertHelp, viMoveHelp, viDeleteHelp, viUndoHelp, keyMap.ExecuteCommand,
+	}
+}
+
+func (v *ViNormalMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		v.ShortHelp(m, keyMap),
+		{viFindHelp, viRepeatHelp, viVisualHelp, keyMap.Up, keyMap.SearchHistoryBackwards, keyMap.Cancel},
+		{keyMap.PageUp, keyMap.PageDown, keyMap.OpenPager, keyMap.SelectItem},
+		{keyMap.CopyLastOutput, keyMap.CopyLastError},
+	}
+}
@@ -0,0 +1,139 @@
+package shell
+
+import (
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ViVisualMode is the vi visual mode of the command input, entered with `v` from
+// the [ViNormalMode], where the motions select text for d, c or y to be applied to
+type ViVisualMode struct {
+	anchor  int       // The position of the input the selection started from
+	pending viCommand // The command being typed
+}
+
+var _ Mode = (*ViVisualMode)(nil)
+
+func (v *ViVisualMode) Enter(m Model) (Model, tea.Cmd) {
+	m.lookBackPartial = ""
+	m.input.Prompt = m.cfg.PromptFunc()
+	m.input.EchoMode = textinput.EchoNormal
+	m.input.Focus()
+	m.clampViCursor()
+
+	return m, nil
+}
+
+func (v *ViVisualMode) Leave(m Model) (Model, tea.Cmd) {
+	v.pending = viCommand{}
+	m.lookBackPartial = m.input.Value()
+	m.input.Blur()
+	return m, nil
+}
+
+func (v *ViVisualMode) Update(m Model, msg tea.Msg) (Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+
+	r := viKey(keyMsg)
+	switch {
+	case key.Matches(keyMsg, m.cfg.KeyMap.ViNormal, m.cfg.KeyMap.Cancel) || r == 'v' && v.pending == viCommand{}:
+		return m, m.Enter(&ViNormalMode{KeepInputContent: true})
+
+	case r == 0:
+		// Keys which aren't vi commands, such as to run the command, do the same as in insert mode
+		v.pending = viCommand{}
+		return (&CommandEntryMode{}).Update(m, msg)
+	}
+
+	if !v.pending.add(r, false) {
+		return m, nil
+	}
+
+	cmd := v.pending
+	v.pending = viCommand{}
+
+	operator := cmd.operator
+	if operator == 0 && (cmd.key == 'd' || cmd.key == 'c' || cmd.key == 'y') {
+		operator = cmd.key
+	}
+
+	value := []rune(m.input.Value())
+	pos := m.input.Position()
+	switch {
+	case operator != 0:
+		// Apply the operator to the selection, which can be repeated with `.` on the same number of characters
+		start, end := v.selection(m)
+		change := viCommand{count: end - start, operator: operator, key: 'l'}
+		m.input.SetCursor(start)
+
+		var teaCmd tea.Cmd
+		m, teaCmd = m.viOperate(change, value, start, end, false)
+		if operator == 'c' {
+			return m, teaCmd
+		}
+		return m, m.Enter(&ViNormalMode{KeepInputContent: true})
+
+	case cmd.key == 'o':
+		// Move to the other end of the selection
+		v.anchor, pos = pos, v.anchor
+		m.input.SetCursor(pos)
+
+	default:
+		if target, _, ok := m.viMotion(value, pos, cmd); ok {
+			m.input.SetCursor(target)
+		}
+	}
+
+	m.clampViCursor()
+	return m, nil
+}
+
+// selection returns the range of the input which is selected, which includes the character under the cursor
+func (v *ViVisualMode) selection(m Model) (start, end int) {
+	start, end = v.anchor, m.input.Position()
+	if end < start {
+		start, end = end, start
+	}
+
+	length := len([]rune(m.input.Value()))
+	end++
+	if end > length {
+		end = length
+	}
+	if start > end {
+		start = end
+	}
+	return start, end
+}
+
+// inputView renders the input with the selection highlighted, in place of the view of the input
+func (v *ViVisualMode) inputView(m Model) string {
+	value := []rune(m.input.Value())
+	start, end := v.selection(m)
+
+	return m.input.PromptStyle.Render(m.input.Prompt) +
+		m.input.TextStyle.Render(string(value[:start])) +
+		m.cfg.Styles.ViSelection.Render(string(value[start:end])) +
+		m.input.TextStyle.Render(string(value[end:]))
+}
+
+func (v *ViVisualMode) AdditionalView(m Model) string {
+	return ""
+}
+
+func (v *ViVisualMode) ShortHelp(m Model, keyMap KeyMap) []key.Binding {
+	return []key.Binding{
+		viMoveHelp, viDeleteHelp, keyMap.ViNormal,
+	}
+}
+
+func (v *ViVisualMode) FullHelp(m Model, keyMap KeyMap) [][]key.Binding {
+	return [][]key.Binding{
+		v.ShortHelp(m, keyMap),
+		{viFindHelp, keyMap.ExecuteCommand},
+	}
+}
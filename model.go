@@ -1,16 +1,15 @@
 package shell
 
 import (
-	"bytes"
 	"context"
 	"io"
-	"os"
 	"strings"
-	"time"
 
-	"github.com/DomBlack/bubble-shell/internal/chanwriter"
 	"github.com/DomBlack/bubble-shell/internal/cobrautils"
 	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/stdin"
+	"github.com/DomBlack/bubble-shell/pkg/interact"
 	"github.com/DomBlack/bubble-shell/pkg/modelid"
 	"github.com/DomBlack/bubble-shell/pkg/tui/autocomplete"
 	"github.com/DomBlack/bubble-shell/pkg/tui/history"
@@ -22,6 +21,9 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// inputPlaceholder is shown in the input when the user has not typed a command
+const inputPlaceholder = "Enter your command here..."
+
 // Model is the main shell model
 type Model struct {
 	id  modelid.ID
@@ -30,8 +32,16 @@ type Model struct {
 	init          bool
 	height, width int
 
-	rootCmd          *cobra.Command
+	executor         *cobrautils.Executor
+	session          *session.Session
+	aliasErr         error // The error from any invalid default aliases, which is reported once the shell starts
 	currentCmdCancel context.CancelFunc
+	currentCmdInput  *stdin.Input
+	asker            *questionAsker
+	shellAccess      *shellAccess
+	recording        *recording
+	lineEditor       *lineEditor
+	vi               *viState
 
 	history      history.Model
 	autocomplete autocomplete.Model
@@ -55,6 +65,12 @@ type Model struct {
 // The key mapping will be updated depending on the [Mode] the shell is in.
 var _ help.KeyMap = Model{}
 
+// New creates the shell model for the given command tree, which can be run with [tea.NewProgram]
+// or embedded in another model.
+//
+// The program must be created with [tea.WithMouseCellMotion] for the shell to receive mouse
+// events, otherwise the history can not be scrolled with the mouse wheel. Note that capturing
+// the mouse stops the terminal's native text selection. [Run] creates the program for you.
 func New(rootCmd *cobra.Command, options ...Option) tea.Model {
 	// Create the config we need
 	cfg := config.Default()
@@ -62,14 +78,27 @@ func New(rootCmd *cobra.Command, options ...Option) tea.Model {
 		option(cfg)
 	}
 
+	return newModel(cfg, rootCmd)
+}
+
+// newModel creates a new shell model using the given config
+func newModel(cfg *config.Config, rootCmd *cobra.Command) Model {
 	input := textinput.New()
-	input.Placeholder = "Enter your command here..."
+	input.Placeholder = inputPlaceholder
 	input.TextStyle = cfg.Styles.Command
 	input.PromptStyle = cfg.Styles.CommandPrompt
 	input.PlaceholderStyle = cfg.Styles.Placeholder
 	input.Cursor.Style = cfg.Styles.Cursor
 	input.Focus()
 
+	// Moving by words and cutting text are handled by the line editor, so cut text goes into the kill ring
+	input.KeyMap.WordForward.SetEnabled(false)
+	input.KeyMap.WordBackward.SetEnabled(false)
+	input.KeyMap.DeleteWordForward.SetEnabled(false)
+	input.KeyMap.DeleteWordBackward.SetEnabled(false)
+	input.KeyMap.DeleteAfterCursor.SetEnabled(false)
+	input.KeyMap.DeleteBeforeCursor.SetEnabled(false)
+
 	searchInput := textinput.New()
 	searchInput.Prompt = "search: "
 	searchInput.TextStyle = cfg.Styles.Search
@@ -77,38 +106,82 @@ func New(rootCmd *cobra.Command, options ...Option) tea.Model {
 	searchInput.PlaceholderStyle = cfg.Styles.Placeholder
 
 	// Reroute cobra to output via our logs
-	cobrautils.InitRootCmd(rootCmd)
+	executor := newExecutor(cfg, rootCmd)
 
 	id := modelid.Next()
+	s, aliasErr := newSession(cfg)
 	return Model{
 		id:  id,
 		cfg: cfg,
 
-		rootCmd: rootCmd,
+		executor: executor,
+		session:  s,
+		aliasErr: aliasErr,
+		asker:    newQuestionAsker(),
+
+		shellAccess: newShellAccess(cfg),
+		recording:   newRecording(cfg.RecordingFile),
+		lineEditor:  newLineEditor(),
+		vi:          &viState{},
 
 		history:      history.New(cfg),
-		autocomplete: autocomplete.New(rootCmd, id),
+		autocomplete: autocomplete.New(executor, s, id),
 		input:        input,
 		searchInput:  searchInput,
 	}
 }
 
+// mouseWheelLines is the number of lines scrolled by each movement of the mouse wheel
+const mouseWheelLines = 3
+
 func (m Model) Init() tea.Cmd {
 	return tea.Batch(
 		textinput.Blink,
 		m.Enter(&CommandEntryMode{}),
 		m.history.Init(),
 		m.autocomplete.Init(),
+		m.reportAliasError(),
+		m.loadAliases(),
+		m.waitForQuestion,
+		m.waitForShellRequest,
 	)
 }
 
+// loadAliases loads the aliases the user has defined from the alias file,
+// which is stored next to the history file
+func (m Model) loadAliases() tea.Cmd {
+	return func() tea.Msg {
+		err := loadAliasFile(m.cfg, m.session)
+		if err == nil {
+			return nil
+		}
+
+		item := history.NewItem("", "error loading alias file", history.ErrorStatus)
+		item.ItemType = history.InternalError
+		item.Error = err
+		return m.history.AppendItem(item)()
+	}
+}
+
+// reportAliasError shows the error from any invalid default aliases in the history
+func (m Model) reportAliasError() tea.Cmd {
+	if m.aliasErr == nil {
+		return nil
+	}
+
+	item := history.NewItem("", "error setting default aliases", history.ErrorStatus)
+	item.ItemType = history.InternalError
+	item.Error = m.aliasErr
+	return m.history.AppendItem(item)
+}
+
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
 	var cmd tea.Cmd
 
 	// Pass all messages to the two inputs
 	// before we do anything else
-	m.input, cmd = m.input.Update(msg)
+	m, cmd = m.updateInput(msg)
 	cmds = append(cmds, cmd)
 
 	m.searchInput, cmd = m.searchInput.Update(msg)
@@ -131,42 +204,107 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.autocomplete, cmd = m.autocomplete.Update(msg)
 		cmds = append(cmds, cmd)
 
+		cmds = append(cmds, m.recording.resize(m, msg.Width, msg.Height))
+
 		// We only want to init once we have a window size
 		m.init = true
 
+		// A command which has taken over the screen needs to know the size of it
+		if takeover, ok := m.mode.(*TakeoverMode); ok {
+			m, cmd = takeover.Update(m, msg)
+			cmds = append(cmds, cmd)
+		}
+
 		return m, tea.Batch(cmds...)
 
 	case ShutdownMsg:
 		if m.id.Matches(msg) {
 			m.shuttingDown = true
+			m.session.KillAllJobs()
+			m.recording.close()
 			return m, tea.Quit
 		}
 
 	case enterModeMsg:
 		if m.id.Matches(msg) {
-			if m.mode == nil {
-				m.mode = msg.Mode
-				return m.mode.Enter(m)
+			return m.switchMode(msg.Mode)
+		}
+		return m, nil
+
+	case currentCmdContextCancelFuncMsg:
+		if m.id.Matches(msg) {
+			m.currentCmdCancel = msg.cancel
+			m.currentCmdInput = msg.input
+		}
+		return m, nil
+
+	case askQuestionMsg:
+		if m.id.Matches(msg) {
+			var mode Mode = &QuestionMode{Request: msg.request}
+			if msg.request.Kind == interact.ModelKind {
+				mode = &TakeoverMode{Request: msg.request}
 			}
 
-			left, leaveCmds := m.mode.Leave(m)
-			left.mode = msg.Mode
-			entered, enterCmds := left.mode.Enter(left)
+			return m, tea.Batch(
+				m.Enter(mode),
+				m.waitForQuestion,
+			)
+		}
+		return m, nil
 
-			return entered, tea.Batch(leaveCmds, enterCmds)
+	case shellRequestMsg:
+		if m.id.Matches(msg) {
+			m, cmd = msg.fn(m)
+			return m, tea.Batch(cmd, m.waitForShellRequest)
 		}
 		return m, nil
 
-	case currentCmdContextCancelFuncMsg:
+	case runJobMsg:
 		if m.id.Matches(msg) {
-			m.currentCmdCancel = msg.cancel
+			return m, m.executeJob(msg.ctx, msg.job, msg.item, msg.list)
 		}
 		return m, nil
 
 	case tea.KeyMsg:
+		_, takenOver := m.mode.(*TakeoverMode)
+		_, fullScreen := m.mode.(fullScreenMode)
+
 		switch {
+		case takenOver:
+			// All keys are passed to the command's model
+
+		case key.Matches(msg, m.cfg.KeyMap.EndOfInput) && m.currentCmdInput != nil:
+			// Handled by the [CommandRunningMode]
+
 		case key.Matches(msg, m.cfg.KeyMap.Quit):
 			return m, m.Shutdown
+
+		case fullScreen:
+			// Scrolling is handled by the mode
+
+		case key.Matches(msg, m.cfg.KeyMap.PageUp):
+			m.history.ScrollBy(m.history.Height() - 1)
+
+		case key.Matches(msg, m.cfg.KeyMap.PageDown):
+			m.history.ScrollBy(1 - m.history.Height())
+
+		// Home and End move the cursor within the input, so only scroll
+		// when there is nothing to move through or we're scrolled back
+		case key.Matches(msg, m.cfg.KeyMap.Home) && m.input.Value() == "":
+			m.history.ScrollToTop()
+
+		case key.Matches(msg, m.cfg.KeyMap.End) && m.history.IsScrolledBack():
+			m.history.ScrollToBottom()
+		}
+
+	case tea.MouseMsg:
+		if _, fullScreen := m.mode.(fullScreenMode); !fullScreen {
+			switch msg.Type {
+			case tea.MouseWheelUp:
+				m.history.ScrollBy(mouseWheelLines)
+			case tea.MouseWheelDown:
+				m.history.ScrollBy(-mouseWheelLines)
+			}
 		}
 	}
 
@@ -184,7 +322,31 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// switchMode leaves the current mode and enters the given mode straight away, which
+// [Model.Enter] does once the update loop processes the message it returns
+func (m Model) switchMode(mode Mode) (Model, tea.Cmd) {
+	if m.mode == nil {
+		m.mode = mode
+		return m.mode.Enter(m)
+	}
+
+	left, leaveCmds := m.mode.Leave(m)
+	left.mode = mode
+	entered, enterCmds := left.mode.Enter(left)
+
+	return entered, tea.Batch(leaveCmds, enterCmds)
+}
+
 func (m Model) View() string {
+	view := m.view()
+	if m.init {
+		m.recording.frame(view)
+	}
+	return view
+}
+
+// view renders the shell
+func (m Model) view() string {
 	if m.mode == nil {
 		// we've not finished init yet
 		return ""
@@ -194,10 +356,36 @@ func (m Model) View() string {
 		return lipgloss.NewStyle().Foreground(lipgloss.Color("205")).Render("Waiting for window size...")
 	}
 
+	// Full screen modes, such as a command which has taken over the screen, are the only thing shown
+	if _, ok := m.mode.(fullScreenMode); ok {
+		return m.mode.AdditionalView(m)
+	}
+
 	historyView := m.history.View()
 	input := m.input.View()
 	modeView := m.mode.AdditionalView(m)
 
+	// Questions asked by the running command are shown in place of the input
+	if _, asking := m.mode.(*QuestionMode); asking {
+		input = ""
+	}
+
+	// If we're an inline shell and the previous command is still running, wait for it to finish
+	// unless it's running as a background job or it's reading what the user types
+	lastCmd := m.history.Lookback(1)
+	if m.cfg.InlineShell && !lastCmd.LoadedHistory && !lastCmd.Started.IsZero() && lastCmd.Finished.IsZero() && lastCmd.Job == 0 &&
+		(m.currentCmdInput == nil || !m.currentCmdInput.Used()) {
+		input = ""
+	}
+
+	// The text selected in the vi visual mode is highlighted within the input
+	if visual, ok := m.mode.(*ViVisualMode); ok {
+		input = visual.inputView(m)
+	}
+	if input != "" {
+		input = m.viIndicator() + input
+	}
+
 	if !m.cfg.InlineShell {
 		// Fit the history to the screen based on the output of the autocomplete and if we're showing the search input
 		neededHistoryHeight := m.height - 1 // one for the prompt
@@ -211,12 +399,6 @@ func (m Model) View() string {
 		}
 	}
 
-	// If we're an inline shell and the previous command is still running, wait for it to finish
-	lastCmd := m.history.Lookback(1)
-	if m.cfg.InlineShell && !lastCmd.LoadedHistory && !lastCmd.Started.IsZero() && lastCmd.Finished.IsZero() {
-		input = ""
-	}
-
 	// Now render all the parts vertically
 	parts := make([]string, 0, 3)
 	parts = append(parts, historyView)
@@ -231,39 +413,13 @@ func (m Model) View() string {
 	return lipgloss.JoinVertical(lipgloss.Top, parts...)
 }
 
-func (m Model) ExecuteCommand(cmd history.Item) tea.Cmd {
-	ctx, cancel := context.WithCancel(m.cfg.RootContext)
-	w := chanwriter.New()
-
-	return tea.Batch(
-		func() tea.Msg {
-			return currentCmdContextCancelFuncMsg{m.id, cancel}
-		},
-		chanwriter.Read(w, m.history.StreamOutputFor(cmd)),
-		func() tea.Msg {
-			defer cancel()
-			defer func() { _ = w.Close() }()
-
-			stdoutBuffer := new(bytes.Buffer)
-			dualW := io.MultiWriter(stdoutBuffer, w)
-
-			err := cobrautils.ExecuteCmd(ctx, m.rootCmd, cmd.Line, os.Stdin, dualW, dualW)
-			cmd.Finished = time.Now()
-			if err != nil {
-				cmd.Status = history.ErrorStatus
-				cmd.Error = err
-			}
-			cmd.Status = history.SuccessStatus
-
-			// Capture the stdout
-			cmd.Output = strings.TrimSpace(string(stdoutBuffer.Bytes()))
-
-			return tea.Sequence(
-				m.history.UpdateItem(cmd),    // Create an UpdateItem [tea.Cmd]
-				m.Enter(&CommandEntryMode{}), // Then switch back to command entry mode
-			)()
-		},
-	)
+// ExportTranscript writes a transcript of the commands run in this session to w,
+// with their output and errors, in the given format.
+//
+// As the model is a value, this should be called on the model returned by the
+// [tea.Program] once it has finished, such as to save a transcript on exit.
+func (m Model) ExportTranscript(w io.Writer, format history.ExportFormat) error {
+	return m.history.Export(w, format)
 }
 
 // Shutdown is a [tea.Cmd] to shutdown the shell cleanly
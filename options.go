@@ -2,10 +2,12 @@ package shell
 
 import (
 	"context"
+	"io"
 
 	"github.com/DomBlack/bubble-shell/internal/config"
 	"github.com/DomBlack/bubble-shell/pkg/config/keymap"
 	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	"github.com/spf13/cobra"
 )
 
 // Option is a function that configures the shell.
@@ -19,6 +21,47 @@ func WithHistoryFile(fileName string) Option {
 	}
 }
 
+// WithAliases adds default aliases to the shell, mapping from the alias name
+// to the command it expands to, such as `"dep": "deploy --env staging"`.
+//
+// Users can override or remove these aliases using the `alias` and `unalias`
+// commands, however unlike the aliases they define, these are not saved
+// to the alias file.
+//
+// Any invalid aliases are skipped, and reported as an error once the shell starts.
+func WithAliases(aliases map[string]string) Option {
+	return func(o *config.Config) {
+		if o.Aliases == nil {
+			o.Aliases = make(map[string]string, len(aliases))
+		}
+		for name, value := range aliases {
+			o.Aliases[name] = value
+		}
+	}
+}
+
+// WithRecording records everything the shell renders into the file at path, which is
+// created or truncated when the shell starts, in the asciicast v2 format used by asciinema.
+//
+// Recordings can be played back within the shell using the `replay` command, or with
+// `asciinema play`, making them useful for demos and training without a live environment.
+func WithRecording(path string) Option {
+	return func(o *config.Config) {
+		o.RecordingFile = path
+	}
+}
+
+// WithViMode enables vi style editing of the command input, like `set -o vi` in bash.
+//
+// Commands are typed in insert mode, with the [KeyMap] ViNormal binding (escape by default)
+// switching to normal mode where the vi motions and operators edit the command. The mode
+// the input is in is shown before the prompt.
+func WithViMode() Option {
+	return func(o *config.Config) {
+		o.ViMode = true
+	}
+}
+
 // WithNoHistory disables history for the shell
 func WithNoHistory() Option {
 	return func(o *config.Config) {
@@ -93,6 +136,56 @@ func WithInlineShell() Option {
 	}
 }
 
+// WithOutput sets where the shell is being rendered, which must be the same writer given to the
+// bubbletea program with tea.WithOutput. By default this is os.Stdout.
+//
+// The shell writes some escape sequences for the terminal itself, such as the OSC 52 sequence
+// to copy to the clipboard. Each sequence is written with a single write, as bubbletea writes
+// each frame it renders, so as long as w does not interleave concurrent writes (which an
+// *os.File never does) the sequences are never written in the middle of a frame.
+func WithOutput(w io.Writer) Option {
+	if w == nil {
+		panic("w cannot be nil")
+	}
+
+	return func(o *config.Config) {
+		o.Output = w
+	}
+}
+
+// WithCommandFactory sets a function which the shell calls to build a new command tree for
+// every command it executes and every autocomplete request, rather than reusing the root
+// command passed to [New], which will be ignored and can be nil.
+//
+// Cobra stores the values of flags on the commands themselves, so when reusing a single tree
+// the shell can only reset some of that state between executions, and a command can not run
+// while the same command, or one sharing a flag with it, is running as a background job.
+// Using a new tree each time means nothing can leak between executions, and commands never
+// have to wait for each other.
+//
+// The function must return a new tree each time it is called.
+func WithCommandFactory(newRootCmd func() *cobra.Command) Option {
+	if newRootCmd == nil {
+		panic("newRootCmd cannot be nil")
+	}
+
+	return func(o *config.Config) {
+		o.CommandFactory = newRootCmd
+	}
+}
+
+// WithStdoutCapture captures anything commands write directly to os.Stdout or os.Stderr,
+// such as with fmt.Println, as part of their output.
+//
+// This is intended for existing commands which do not write to cmd.OutOrStdout(). As it
+// replaces os.Stdout and os.Stderr for the whole process while a command is running, commands
+// from all the shells using this option, including background jobs, are run one at a time.
+func WithStdoutCapture() Option {
+	return func(o *config.Config) {
+		o.CaptureStdout = true
+	}
+}
+
 // WithBaseContext sets the context that commands will be run with
 // when they are executed by users.
 //
@@ -115,3 +208,14 @@ func WithPromptFunc(promptFunc func() string) Option {
 		o.PromptFunc = promptFunc
 	}
 }
+
+// WithContinueOnError causes [RunScript] to keep running the remaining lines of
+// a script after a line fails, rather than stopping at the first failure.
+//
+// The error from each line which fails is still reported, and [RunScript]
+// returns an error once the script has finished.
+func WithContinueOnError() Option {
+	return func(o *config.Config) {
+		o.ContinueOnError = true
+	}
+}
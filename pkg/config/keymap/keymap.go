@@ -31,6 +31,62 @@ type KeyMap struct {
 	AutoComplete         key.Binding // AutoComplete is a binding for the user to autocomplete their current command or cycle through autocompletions
 	PreviousAutoComplete key.Binding // PreviousAutoComplete is a binding for the user to cycle through previous autocompletions
 
+	// Bindings used to edit the command being typed
+	WordForward      key.Binding // WordForward is a binding for the user to move the cursor to the end of the next word
+	WordBackward     key.Binding // WordBackward is a binding for the user to move the cursor to the start of the previous word
+	KillWordBackward key.Binding // KillWordBackward is a binding for the user to cut the word before the cursor into the kill ring
+	KillWordForward  key.Binding // KillWordForward is a binding for the user to cut the word after the cursor into the kill ring
+	KillToStart      key.Binding // KillToStart is a binding for the user to cut everything before the cursor into the kill ring
+	KillToEnd        key.Binding // KillToEnd is a binding for the user to cut everything after the cursor into the kill ring
+	Yank             key.Binding // Yank is a binding for the user to paste the most recently cut text from the kill ring
+	YankPop          key.Binding // YankPop is a binding for the user to replace the text just pasted with the text cut before it
+	TransposeChars   key.Binding // TransposeChars is a binding for the user to swap the character before the cursor with the one under it
+	Undo             key.Binding // Undo is a binding for the user to undo their last change to the command
+	Redo             key.Binding // Redo is a binding for the user to redo the last change they undid
+
+	// ViNormal is a binding for the user to leave insert mode for the normal mode when vi editing is enabled.
+	// It takes priority over Cancel while vi editing is enabled, other than when a command is running.
+	ViNormal key.Binding
+
+	OpenPager key.Binding // OpenPager is a binding for the user to open the output of the last command in the pager
+
+	// Bindings used while scrolling through output
+	PageUp   key.Binding // PageUp is a binding for the user to scroll up a page
+	PageDown key.Binding // PageDown is a binding for the user to scroll down a page
+	Home     key.Binding // Home is a binding for the user to scroll to the top
+	End      key.Binding // End is a binding for the user to scroll to the bottom
+
+	// Bindings used within the pager
+	PagerSearch        key.Binding // PagerSearch is a binding for the user to search the output in the pager
+	PagerNextMatch     key.Binding // PagerNextMatch is a binding for the user to jump to the next match of the search
+	PagerPreviousMatch key.Binding // PagerPreviousMatch is a binding for the user to jump to the previous match of the search
+	PagerQuit          key.Binding // PagerQuit is a binding for the user to close the pager
+
+	// Bindings used while replaying a recording
+	ReplayPause  key.Binding // ReplayPause is a binding for the user to pause or resume the replay
+	ReplayFaster key.Binding // ReplayFaster is a binding for the user to double the speed of the replay
+	ReplaySlower key.Binding // ReplaySlower is a binding for the user to halve the speed of the replay
+	ReplayQuit   key.Binding // ReplayQuit is a binding for the user to stop the replay
+
+	SelectItem key.Binding // SelectItem is a binding for the user to start selecting items in the history
+
+	CopyLastOutput key.Binding // CopyLastOutput is a binding for the user to copy the output of the last command to the clipboard
+	CopyLastError  key.Binding // CopyLastError is a binding for the user to copy the last error, including its stack trace, to the clipboard
+
+	// Bindings used while selecting items in the history
+	ToggleFold key.Binding // ToggleFold is a binding for the user to fold or unfold the output of the selected item
+	RerunItem  key.Binding // RerunItem is a binding for the user to run the command of the selected item again
+	EditItem   key.Binding // EditItem is a binding for the user to edit the command of the selected item in the input
+	CopyItem   key.Binding // CopyItem is a binding for the user to copy the command of the selected item to the clipboard
+	DeleteItem key.Binding // DeleteItem is a binding for the user to delete the selected item from the history
+
+	CopyItemOutput key.Binding // CopyItemOutput is a binding for the user to copy the output of the selected item to the clipboard
+	CopyItemError  key.Binding // CopyItemError is a binding for the user to copy the error of the selected item to the clipboard
+
+	// EndOfInput is a binding for the user to end the input of the command which is
+	// running, so it reads an EOF. When a command is running, it takes priority over Quit.
+	EndOfInput key.Binding
+
 	// Quit is the binding for the user to quit the shell no matter what they are doing
 	Quit key.Binding
 }
@@ -87,6 +143,186 @@ var Default = KeyMap{
 		key.WithHelp("shift+tab", "previous autocomplete"),
 	),
 
+	WordForward: key.NewBinding(
+		key.WithKeys("alt+f", "alt+right"),
+		key.WithHelp("alt+f", "next word"),
+	),
+
+	WordBackward: key.NewBinding(
+		key.WithKeys("alt+b", "alt+left"),
+		key.WithHelp("alt+b", "previous word"),
+	),
+
+	KillWordBackward: key.NewBinding(
+		key.WithKeys("ctrl+w", "alt+backspace"),
+		key.WithHelp("ctrl+w", "cut previous word"),
+	),
+
+	KillWordForward: key.NewBinding(
+		key.WithKeys("alt+d", "alt+delete"),
+		key.WithHelp("alt+d", "cut next word"),
+	),
+
+	KillToStart: key.NewBinding(
+		key.WithKeys("ctrl+u"),
+		key.WithHelp("ctrl+u", "cut to start"),
+	),
+
+	KillToEnd: key.NewBinding(
+		key.WithKeys("ctrl+k"),
+		key.WithHelp("ctrl+k", "cut to end"),
+	),
+
+	Yank: key.NewBinding(
+		key.WithKeys("ctrl+y"),
+		key.WithHelp("ctrl+y", "paste"),
+	),
+
+	YankPop: key.NewBinding(
+		key.WithKeys("alt+y"),
+		key.WithHelp("alt+y", "paste earlier cut"),
+	),
+
+	TransposeChars: key.NewBinding(
+		key.WithKeys("ctrl+t"),
+		key.WithHelp("ctrl+t", "swap characters"),
+	),
+
+	Undo: key.NewBinding(
+		key.WithKeys("ctrl+_"),
+		key.WithHelp("ctrl+_", "undo"),
+	),
+
+	Redo: key.NewBinding(
+		key.WithKeys("alt+_"),
+		key.WithHelp("alt+_", "redo"),
+	),
+
+	ViNormal: key.NewBinding(
+		key.WithKeys("esc"),
+		key.WithHelp("esc", "normal mode"),
+	),
+
+	OpenPager: key.NewBinding(
+		key.WithKeys("ctrl+o"),
+		key.WithHelp("ctrl+o", "open output in pager"),
+	),
+
+	PageUp: key.NewBinding(
+		key.WithKeys("pgup"),
+		key.WithHelp("pgup", "page up"),
+	),
+
+	PageDown: key.NewBinding(
+		key.WithKeys("pgdown"),
+		key.WithHelp("pgdown", "page down"),
+	),
+
+	Home: key.NewBinding(
+		key.WithKeys("home"),
+		key.WithHelp("home", "top"),
+	),
+
+	End: key.NewBinding(
+		key.WithKeys("end"),
+		key.WithHelp("end", "bottom"),
+	),
+
+	PagerSearch: key.NewBinding(
+		key.WithKeys("/"),
+		key.WithHelp("/", "search"),
+	),
+
+	PagerNextMatch: key.NewBinding(
+		key.WithKeys("n"),
+		key.WithHelp("n", "next match"),
+	),
+
+	PagerPreviousMatch: key.NewBinding(
+		key.WithKeys("N"),
+		key.WithHelp("N", "previous match"),
+	),
+
+	PagerQuit: key.NewBinding(
+		key.WithKeys("q"),
+		key.WithHelp("q", "close pager"),
+	),
+
+	ReplayPause: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "play/pause"),
+	),
+
+	ReplayFaster: key.NewBinding(
+		key.WithKeys("+", "="),
+		key.WithHelp("+", "faster"),
+	),
+
+	ReplaySlower: key.NewBinding(
+		key.WithKeys("-", "_"),
+		key.WithHelp("-", "slower"),
+	),
+
+	ReplayQuit: key.NewBinding(
+		key.WithKeys("q"),
+		key.WithHelp("q", "stop replay"),
+	),
+
+	SelectItem: key.NewBinding(
+		key.WithKeys("alt+up", "ctrl+up"),
+		key.WithHelp("alt+↑", "select history item"),
+	),
+
+	CopyLastOutput: key.NewBinding(
+		key.WithKeys("alt+c"),
+		key.WithHelp("alt+c", "copy last output"),
+	),
+
+	CopyLastError: key.NewBinding(
+		key.WithKeys("alt+e"),
+		key.WithHelp("alt+e", "copy last error"),
+	),
+
+	ToggleFold: key.NewBinding(
+		key.WithKeys(" "),
+		key.WithHelp("space", "fold/unfold"),
+	),
+
+	RerunItem: key.NewBinding(
+		key.WithKeys("r"),
+		key.WithHelp("r", "run again"),
+	),
+
+	EditItem: key.NewBinding(
+		key.WithKeys("e"),
+		key.WithHelp("e", "edit command"),
+	),
+
+	CopyItem: key.NewBinding(
+		key.WithKeys("c"),
+		key.WithHelp("c", "copy command"),
+	),
+
+	DeleteItem: key.NewBinding(
+		key.WithKeys("d", "delete"),
+		key.WithHelp("d", "delete"),
+	),
+
+	CopyItemOutput: key.NewBinding(
+		key.WithKeys("o"),
+		key.WithHelp("o", "copy output"),
+	),
+
+	CopyItemError: key.NewBinding(
+		key.WithKeys("x"),
+		key.WithHelp("x", "copy error"),
+	),
+
+	EndOfInput: key.NewBinding(
+		key.WithKeys("ctrl+d"),
+		key.WithHelp("ctrl+d", "end input"),
+	),
+
 	Quit: key.NewBinding(
 		key.WithKeys("ctrl+d"),
 		key.WithHelp("ctrl+d", "quit"),
@@ -1,6 +1,8 @@
 package styles
 
 import (
+	"reflect"
+
 	. "github.com/charmbracelet/lipgloss"
 )
 
@@ -18,10 +20,15 @@ type Styles struct {
 	SearchPrompt  Style // The style for the search prompt
 	Search        Style // The style for the text in the search input
 
+	ViModeIndicator Style // The style for the vi mode shown before the prompt when vi editing is enabled
+	ViSelection     Style // The style for the text selected in the vi visual mode
+
 	// Styles for the history
 	HistoricPrompt Style // The style for the prompt in the history
 	HistoricLine   Style // The style for a historic command executed by the user
 	HistoricTime   Style // The style for the time a command was executed
+	SelectedItem   Style // The style wrapped around the history item which is selected
+	FoldedSummary  Style // The style for the summary of a folded history item
 
 	// Styles for errors being printed
 	ErrorTitle         Style // The style for the title of an error
@@ -30,6 +37,25 @@ type Styles struct {
 	StackFramePackage  Style // The style for the name of a module in an error
 	StackFrameFunction Style // The style for the name of a function in an error
 
+	// Styles for questions asked by commands
+	QuestionLabel          Style // The style for the question being asked
+	QuestionOption         Style // The style for the options the user can pick from
+	QuestionSelectedOption Style // The style for the option the user currently has selected
+
+	// Styles for the progress reported by commands
+	ProgressLabel    Style // The style for the label of an operation
+	ProgressSpinner  Style // The style for the spinner shown for operations without a known size, and the mark shown once they finish
+	ProgressBar      Style // The style for the completed part of a progress bar
+	ProgressBarEmpty Style // The style for the remaining part of a progress bar
+
+	// Styles for the pager
+	PagerStatus Style // The style for the status line at the bottom of the pager
+	PagerMatch  Style // The style for the line of the current search match
+
+	ReplayStatus Style // The style for the status line at the bottom of a replay
+
+	ScrollIndicator Style // The style for the indicator shown while the history is scrolled back
+
 	// Misc Styles
 	InternalError Style // The styling for an internal error
 }
@@ -44,9 +70,14 @@ var Default = Styles{
 	SearchPrompt:  NewStyle(),
 	Search:        NewStyle(),
 
+	ViModeIndicator: NewStyle().Foreground(Color("240")),
+	ViSelection:     NewStyle().Reverse(true),
+
 	HistoricPrompt: NewStyle().Foreground(Color("91")),
 	HistoricLine:   NewStyle().Foreground(Color("244")),
 	HistoricTime:   NewStyle().Foreground(Color("240")).Align(Right),
+	SelectedItem:   NewStyle().Border(ThickBorder(), false, false, false, true).BorderForeground(Color("205")),
+	FoldedSummary:  NewStyle().Foreground(Color("240")).Italic(true),
 
 	ErrorTitle:         NewStyle().Foreground(Color("#FF0000")).Bold(true),
 	ErrorMessage:       NewStyle().Foreground(Color("#FF8888")),
@@ -54,5 +85,78 @@ var Default = Styles{
 	StackFramePackage:  NewStyle().Foreground(Color("90")),
 	StackFrameFunction: NewStyle().Foreground(Color("35")),
 
+	QuestionLabel:          NewStyle().Foreground(Color("205")).Bold(true),
+	QuestionOption:         NewStyle().Foreground(Color("244")),
+	QuestionSelectedOption: NewStyle().Foreground(Color("205")),
+
+	ProgressLabel:    NewStyle().Foreground(Color("244")),
+	ProgressSpinner:  NewStyle().Foreground(Color("205")),
+	ProgressBar:      NewStyle().Foreground(Color("205")),
+	ProgressBarEmpty: NewStyle().Foreground(Color("240")),
+
+	PagerStatus: NewStyle().Reverse(true),
+	PagerMatch:  NewStyle().Foreground(Color("#000000")).Background(Color("205")),
+
+	ReplayStatus: NewStyle().Reverse(true),
+
+	ScrollIndicator: NewStyle().Foreground(Color("#000000")).Background(Color("244")),
+
 	InternalError: NewStyle().Foreground(Color("196")).Bold(true).Blink(true),
 }
+
+// Plain is a set of styles with no colors or formatting, which is used
+// when output is not being rendered to a terminal, such as when running scripts
+var Plain = Styles{
+	Placeholder: NewStyle(),
+	Cursor:      NewStyle(),
+
+	CommandPrompt: NewStyle(),
+	Command:       NewStyle(),
+	SearchPrompt:  NewStyle(),
+	Search:        NewStyle(),
+
+	ViModeIndicator: NewStyle(),
+	ViSelection:     NewStyle(),
+
+	HistoricPrompt: NewStyle(),
+	HistoricLine:   NewStyle(),
+	HistoricTime:   NewStyle().Align(Right),
+	SelectedItem:   NewStyle().Border(NormalBorder(), false, false, false, true),
+	FoldedSummary:  NewStyle(),
+
+	ErrorTitle:         NewStyle(),
+	ErrorMessage:       NewStyle(),
+	ErrorDetails:       NewStyle().PaddingLeft(2),
+	StackFramePackage:  NewStyle(),
+	StackFrameFunction: NewStyle(),
+
+	QuestionLabel:          NewStyle(),
+	QuestionOption:         NewStyle(),
+	QuestionSelectedOption: NewStyle(),
+
+	ProgressLabel:    NewStyle(),
+	ProgressSpinner:  NewStyle(),
+	ProgressBar:      NewStyle(),
+	ProgressBarEmpty: NewStyle(),
+
+	PagerStatus: NewStyle(),
+	PagerMatch:  NewStyle(),
+
+	ReplayStatus: NewStyle(),
+
+	ScrollIndicator: NewStyle(),
+
+	InternalError: NewStyle(),
+}
+
+// WithRenderer returns a copy of the styles which render using the given renderer,
+// such as one with a different color profile to the terminal the shell is running in
+func (s Styles) WithRenderer(r *Renderer) Styles {
+	v := reflect.ValueOf(&s).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if style, ok := v.Field(i).Interface().(Style); ok {
+			v.Field(i).Set(reflect.ValueOf(style.Renderer(r)))
+		}
+	}
+	return s
+}
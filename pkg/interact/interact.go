@@ -0,0 +1,194 @@
+// Package interact allows commands run by the shell to interact with the user
+// while they are running, such as asking for confirmation before doing
+// something destructive, or handing the screen over to their own [tea.Model].
+//
+// Each function pauses the command while the shell shows the question to the
+// user, and returns once the user has answered it. The context passed must be
+// the context of the command, from cmd.Context(), which carries the shell
+// the command is running in.
+//
+//	if ok, err := interact.Confirm(cmd.Context(), "Delete the database?"); err != nil || !ok {
+//		return err
+//	}
+package interact
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/errors"
+)
+
+// Sentinel errors are created without a stack trace, as they are wrapped
+// with the stack of where they are returned from
+var (
+	// ErrNotInteractive is returned when the command is not being run by the
+	// interactive shell, such as when it is run by a script, so there is no
+	// user to answer the question
+	ErrNotInteractive = stderrors.New("unable to ask a question as the command is not running interactively")
+
+	// ErrCancelled is returned when the user cancels the question rather than answering it
+	ErrCancelled = stderrors.New("question cancelled by the user")
+)
+
+// Kind is the kind of question being asked
+type Kind uint8
+
+const (
+	ConfirmKind  Kind = iota // A yes or no question
+	PromptKind               // A question answered with a line of text
+	PasswordKind             // A question answered with a line of text which is masked as it is typed
+	SelectKind               // A question answered by picking one of the options
+	ModelKind                // A [tea.Model] which takes over the screen until it quits
+)
+
+// Request is a question being asked of the user, which the shell
+// must answer by calling [Request.Respond] or [Request.Cancel].
+type Request struct {
+	Kind    Kind      // The kind of question
+	Label   string    // The question to show the user
+	Options []string  // The options the user can pick from for a [SelectKind] question
+	Model   tea.Model // The model to run for a [ModelKind] request
+
+	response chan response
+}
+
+// Answer is the answer the user gave to a [Request]
+type Answer struct {
+	Confirmed bool   // If the user said yes to a [ConfirmKind] question
+	Text      string // The text the user entered, or the option they picked
+	Index     int    // The index of the option picked for a [SelectKind] question
+
+	Model tea.Model // The final state of the model for a [ModelKind] request
+}
+
+type response struct {
+	answer Answer
+	err    error
+}
+
+// Respond answers the request with the answer the user gave
+func (r *Request) Respond(answer Answer) {
+	select {
+	case r.response <- response{answer: answer}:
+	default:
+		// The request has already been answered
+	}
+}
+
+// Cancel answers the request with [ErrCancelled]
+func (r *Request) Cancel() {
+	select {
+	case r.response <- response{err: ErrCancelled}:
+	default:
+		// The request has already been answered
+	}
+}
+
+// Asker is implemented by the shell to show requests to the user
+type Asker interface {
+	// Ask shows the request to the user, returning once the request has been
+	// accepted by the shell rather than when it has been answered. If the
+	// context is done before the request is accepted, it returns the context's error.
+	Ask(ctx context.Context, req *Request) error
+}
+
+// Confirm asks the user a yes or no question, returning true if they said yes
+func Confirm(ctx context.Context, question string) (bool, error) {
+	answer, err := ask(ctx, &Request{Kind: ConfirmKind, Label: question})
+	return answer.Confirmed, err
+}
+
+// Prompt asks the user to enter a line of text
+func Prompt(ctx context.Context, label string) (string, error) {
+	answer, err := ask(ctx, &Request{Kind: PromptKind, Label: label})
+	return answer.Text, err
+}
+
+// Password asks the user to enter a line of text, which is masked as they type it
+func Password(ctx context.Context, label string) (string, error) {
+	answer, err := ask(ctx, &Request{Kind: PasswordKind, Label: label})
+	return answer.Text, err
+}
+
+// Select asks the user to pick one of the options, returning the index of the option picked
+func Select(ctx context.Context, label string, options []string) (int, error) {
+	if len(options) == 0 {
+		return -1, errors.New("no options to select from")
+	}
+
+	answer, err := ask(ctx, &Request{Kind: SelectKind, Label: label, Options: options})
+	if err != nil {
+		return -1, err
+	}
+	return answer.Index, nil
+}
+
+// RunModel hands the screen over to the model until it quits, allowing commands to show
+// richer views than their output, such as an interactive table or a file picker. It returns
+// the final state of the model, so the command can get the result from it.
+//
+// The model works as it would in its own bubbletea program; it receives all the key presses
+// and the size of the screen, and quits by returning [tea.Quit] (either on its own or within
+// [tea.Batch] or [tea.Sequence]). Once it has quit its final view is written to out, normally
+// cmd.OutOrStdout(), so it is kept in the history of the shell. If out is nil the final view is
+// not written.
+func RunModel(ctx context.Context, out io.Writer, model tea.Model) (tea.Model, error) {
+	if model == nil {
+		return nil, errors.New("no model to run")
+	}
+
+	answer, err := ask(ctx, &Request{Kind: ModelKind, Model: model})
+	if err != nil {
+		return model, err
+	}
+
+	if view := answer.Model.View(); out != nil && view != "" {
+		if _, err := fmt.Fprintln(out, view); err != nil {
+			return answer.Model, errors.Wrap(err, "unable to write the final view of the model")
+		}
+	}
+
+	return answer.Model, nil
+}
+
+// ask sends the request to the shell carried by the context and waits for the answer
+func ask(ctx context.Context, req *Request) (Answer, error) {
+	asker := FromContext(ctx)
+	if asker == nil {
+		return Answer{}, errors.WithStack(ErrNotInteractive)
+	}
+
+	req.response = make(chan response, 1)
+	if err := asker.Ask(ctx, req); err != nil {
+		return Answer{}, err
+	}
+
+	select {
+	case resp := <-req.response:
+		return resp.answer, errors.WithStack(resp.err)
+	case <-ctx.Done():
+		return Answer{}, errors.WithStack(ctx.Err())
+	}
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying the asker, which is
+// used by the shell to allow the commands it runs to ask questions
+func NewContext(ctx context.Context, asker Asker) context.Context {
+	return context.WithValue(ctx, contextKey{}, asker)
+}
+
+// FromContext returns the asker from the context, or nil if there is none
+func FromContext(ctx context.Context) Asker {
+	if ctx == nil {
+		return nil
+	}
+
+	asker, _ := ctx.Value(contextKey{}).(Asker)
+	return asker
+}
@@ -0,0 +1,109 @@
+package interact
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/errors"
+)
+
+// answerWith is an [Asker] which answers every request by calling the function
+type answerWith func(req *Request)
+
+func (a answerWith) Ask(_ context.Context, req *Request) error {
+	go a(req)
+	return nil
+}
+
+func TestQuestions(t *testing.T) {
+	var asked []Kind
+	ctx := NewContext(context.Background(), answerWith(func(req *Request) {
+		asked = append(asked, req.Kind)
+
+		switch req.Kind {
+		case ConfirmKind:
+			req.Respond(Answer{Confirmed: true})
+		case SelectKind:
+			req.Respond(Answer{Index: len(req.Options) - 1, Text: req.Options[len(req.Options)-1]})
+		default:
+			req.Respond(Answer{Text: req.Label + " answer"})
+		}
+	}))
+
+	if ok, err := Confirm(ctx, "sure?"); err != nil || !ok {
+		t.Errorf("expected Confirm to return true but got %v, %v", ok, err)
+	}
+	if text, err := Prompt(ctx, "name"); err != nil || text != "name answer" {
+		t.Errorf("expected Prompt to return %q but got %q, %v", "name answer", text, err)
+	}
+	if text, err := Password(ctx, "password"); err != nil || text != "password answer" {
+		t.Errorf("expected Password to return %q but got %q, %v", "password answer", text, err)
+	}
+	if index, err := Select(ctx, "env", []string{"dev", "prod"}); err != nil || index != 1 {
+		t.Errorf("expected Select to return 1 but got %d, %v", index, err)
+	}
+
+	expected := []Kind{ConfirmKind, PromptKind, PasswordKind, SelectKind}
+	if len(asked) != len(expected) {
+		t.Fatalf("expected %d questions to be asked but got %d", len(expected), len(asked))
+	}
+	for i, kind := range expected {
+		if asked[i] != kind {
+			t.Errorf("question %d: expected kind %d but got %d", i, kind, asked[i])
+		}
+	}
+}
+
+func TestQuestionCancelled(t *testing.T) {
+	ctx := NewContext(context.Background(), answerWith(func(req *Request) {
+		req.Cancel()
+		req.Respond(Answer{Text: "ignored"})
+	}))
+
+	if _, err := Prompt(ctx, "name"); !errors.Is(err, ErrCancelled) {
+		t.Errorf("expected ErrCancelled but got %v", err)
+	}
+}
+
+func TestQuestionWithoutShell(t *testing.T) {
+	if _, err := Confirm(context.Background(), "sure?"); !errors.Is(err, ErrNotInteractive) {
+		t.Errorf("expected ErrNotInteractive but got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(NewContext(context.Background(), answerWith(func(req *Request) {})))
+	cancel()
+	if _, err := Prompt(ctx, "name"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled but got %v", err)
+	}
+}
+
+// finalModel is a [tea.Model] which only has a view
+type finalModel string
+
+func (m finalModel) Init() tea.Cmd                       { return nil }
+func (m finalModel) Update(tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
+func (m finalModel) View() string                        { return string(m) }
+
+func TestRunModel(t *testing.T) {
+	ctx := NewContext(context.Background(), answerWith(func(req *Request) {
+		if req.Kind != ModelKind {
+			t.Errorf("expected a ModelKind request but got %d", req.Kind)
+		}
+		req.Respond(Answer{Model: finalModel("picked " + req.Model.View())})
+	}))
+
+	var out bytes.Buffer
+	final, err := RunModel(ctx, &out, finalModel("b"))
+	if err != nil {
+		t.Fatalf("expected no error but got %v", err)
+	}
+
+	if final.View() != "picked b" {
+		t.Errorf("expected the final model to be returned but got %q", final.View())
+	}
+	if out.String() != "picked b\n" {
+		t.Errorf("expected the final view to be written but got %q", out.String())
+	}
+}
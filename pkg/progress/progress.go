@@ -0,0 +1,184 @@
+// Package progress allows commands run by the shell to report the progress of long
+// running operations, which the shell renders as live progress bars or spinners
+// within the command's entry in the history.
+//
+//	bar := progress.Start(cmd.Context(), "uploading", int64(len(files)))
+//	defer bar.Done()
+//
+//	for _, file := range files {
+//		upload(file)
+//		bar.Inc()
+//	}
+package progress
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Bar is the progress of a single operation
+//
+// A Bar is safe for concurrent use, so can be updated from multiple goroutines.
+type Bar struct {
+	mu      sync.Mutex
+	label   string
+	current int64
+	total   int64
+	started time.Time
+	done    bool
+}
+
+// State is a snapshot of the progress of a [Bar]
+type State struct {
+	Label   string    // The label of the operation
+	Current int64     // How much of the operation has been completed
+	Total   int64     // The total size of the operation, or zero if it is not known
+	Started time.Time // When the operation was started
+	Done    bool      // If the operation has finished
+}
+
+// Percent returns how much of the operation has been completed between 0 and 1, or
+// -1 if the total size of the operation is not known, in which case a spinner is shown
+// rather than a progress bar
+func (s State) Percent() float64 {
+	if s.Total <= 0 {
+		return -1
+	}
+
+	switch {
+	case s.Done || s.Current >= s.Total:
+		return 1
+	case s.Current <= 0:
+		return 0
+	default:
+		return float64(s.Current) / float64(s.Total)
+	}
+}
+
+// Start starts reporting the progress of an operation with the given label to the shell
+// carried by the context, which must be the context of the command from cmd.Context().
+//
+// If the total size of the operation is not known, total should be zero and a spinner
+// is shown until [Bar.Done] is called. If the command is not being run by the interactive
+// shell, the progress is not shown, however the returned bar can still be used.
+func Start(ctx context.Context, label string, total int64) *Bar {
+	bar := &Bar{
+		label:   label,
+		total:   total,
+		started: time.Now(),
+	}
+
+	if group := FromContext(ctx); group != nil {
+		group.add(bar)
+	}
+
+	return bar
+}
+
+// Inc increments the progress by one
+func (b *Bar) Inc() {
+	b.Add(1)
+}
+
+// Add increments the progress by n
+func (b *Bar) Add(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current += n
+}
+
+// Set sets the progress to n
+func (b *Bar) Set(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.current = n
+}
+
+// SetTotal sets the total size of the operation, which can be
+// used once the size is known after the operation has started
+func (b *Bar) SetTotal(total int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.total = total
+}
+
+// Done marks the operation as finished
+func (b *Bar) Done() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.done = true
+}
+
+// State returns a snapshot of the progress of the bar
+func (b *Bar) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return State{
+		Label:   b.label,
+		Current: b.current,
+		Total:   b.total,
+		Started: b.started,
+		Done:    b.done,
+	}
+}
+
+// Group is the set of bars started by a command, which is used
+// by the shell to render the progress of the command
+type Group struct {
+	mu   sync.Mutex
+	bars []*Bar
+}
+
+// NewGroup creates a new empty group
+func NewGroup() *Group {
+	return &Group{}
+}
+
+// add adds the bar to the group
+func (g *Group) add(bar *Bar) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.bars = append(g.bars, bar)
+}
+
+// States returns a snapshot of the state of each bar in the
+// group, in the order they were started
+func (g *Group) States() []State {
+	if g == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	states := make([]State, len(g.bars))
+	for i, bar := range g.bars {
+		states[i] = bar.State()
+	}
+	return states
+}
+
+type contextKey struct{}
+
+// NewContext returns a new context carrying the group, which the
+// bars started with the context are added to
+func NewContext(ctx context.Context, group *Group) context.Context {
+	return context.WithValue(ctx, contextKey{}, group)
+}
+
+// FromContext returns the group from the context, or nil if there is none
+func FromContext(ctx context.Context) *Group {
+	if ctx == nil {
+		return nil
+	}
+
+	group, _ := ctx.Value(contextKey{}).(*Group)
+	return group
+}
@@ -0,0 +1,56 @@
+package progress
+
+import (
+	"context"
+	"testing"
+)
+
+func TestStart(t *testing.T) {
+	group := NewGroup()
+	ctx := NewContext(context.Background(), group)
+
+	bar := Start(ctx, "uploading", 4)
+	spinner := Start(ctx, "waiting", 0)
+	_ = Start(context.Background(), "not shown", 1)
+
+	bar.Inc()
+	bar.Add(2)
+	spinner.Done()
+
+	states := group.States()
+	if len(states) != 2 {
+		t.Fatalf("expected 2 bars in the group but got %d", len(states))
+	}
+
+	if states[0].Label != "uploading" || states[0].Current != 3 || states[0].Total != 4 || states[0].Done {
+		t.Errorf("unexpected state for the bar: %+v", states[0])
+	}
+	if states[1].Label != "waiting" || !states[1].Done {
+		t.Errorf("unexpected state for the spinner: %+v", states[1])
+	}
+
+	var nilGroup *Group
+	if states := nilGroup.States(); states != nil {
+		t.Errorf("expected no states from a nil group but got %v", states)
+	}
+}
+
+func TestStatePercent(t *testing.T) {
+	tests := []struct {
+		state    State
+		expected float64
+	}{
+		{state: State{Current: 5}, expected: -1},
+		{state: State{Current: 0, Total: 10}, expected: 0},
+		{state: State{Current: -1, Total: 10}, expected: 0},
+		{state: State{Current: 5, Total: 10}, expected: 0.5},
+		{state: State{Current: 15, Total: 10}, expected: 1},
+		{state: State{Current: 5, Total: 10, Done: true}, expected: 1},
+	}
+
+	for _, test := range tests {
+		if got := test.state.Percent(); got != test.expected {
+			t.Errorf("%+v: expected %v but got %v", test.state, test.expected, got)
+		}
+	}
+}
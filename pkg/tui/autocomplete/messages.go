@@ -2,6 +2,7 @@ package autocomplete
 
 import (
 	"github.com/DomBlack/bubble-shell/pkg/modelid"
+	"github.com/spf13/cobra"
 )
 
 // SingleAutoCompleteOptionMsg is a message that is sent by
@@ -27,6 +28,20 @@ func (msg autoCompleteMsg) ForModelID() modelid.ID {
 	return msg.ID
 }
 
+// optionsComputedMsg is sent once the options for
+// an autocomplete request have been computed
+type optionsComputedMsg struct {
+	ID        modelid.ID
+	Line      string
+	Directive cobra.ShellCompDirective
+	Options   []Option
+	Err       error
+}
+
+func (msg optionsComputedMsg) ForModelID() modelid.ID {
+	return msg.ID
+}
+
 // clearMsg is a message that is sent to the autocomplete
 // model to clear the current autocomplete options
 type clearMsg struct {
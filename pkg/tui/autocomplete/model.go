@@ -2,14 +2,16 @@ package autocomplete
 
 import (
 	"context"
-	"fmt"
 	"io"
 	"math"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/DomBlack/bubble-shell/internal/cobrautils"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
 	"github.com/DomBlack/bubble-shell/pkg/modelid"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -23,7 +25,8 @@ const colPadding = 3
 type Model struct {
 	id            modelid.ID
 	parent        modelid.ID
-	rootCmd       *cobra.Command
+	executor      *cobrautils.Executor
+	session       *session.Session
 	width, height int
 
 	optionStyle         lipgloss.Style
@@ -40,11 +43,12 @@ type Model struct {
 	directive       cobra.ShellCompDirective
 }
 
-func New(rootCmd *cobra.Command, parent modelid.ID) Model {
+func New(executor *cobrautils.Executor, s *session.Session, parent modelid.ID) Model {
 	return Model{
-		id:      modelid.Next(),
-		parent:  parent,
-		rootCmd: rootCmd,
+		id:       modelid.Next(),
+		parent:   parent,
+		executor: executor,
+		session:  s,
 
 		optionStyle:         lipgloss.NewStyle().Foreground(lipgloss.Color("#FFFFFF")),
 		selectedOptionStyle: lipgloss.NewStyle().Foreground(lipgloss.Color("#000000")).Background(lipgloss.Color("#FFFFFF")),
@@ -112,35 +116,56 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 					m.selectedOption = 0
 				}
 			} else {
-				// Input changed; new search
-				directive, options, err := m.computeOptions(msg.Line)
-				if err != nil {
-					// FIXME: handle this error somehow?
-					return m, nil
+				// Input changed; new search, which is computed in the background
+				// as it may need to wait for a running command to finish
+				m.input = msg.Line
+				m.selectedOption = 0
+				m.options = nil
+
+				return m, func() tea.Msg {
+					directive, options, err := m.computeOptions(msg.Line)
+					return optionsComputedMsg{
+						ID:        m.id,
+						Line:      msg.Line,
+						Directive: directive,
+						Options:   options,
+						Err:       err,
+					}
 				}
+			}
+		}
 
-				length := 0
-				hasDescriptions := false
-				for _, option := range options {
-					if len(option.Name) > length {
-						length = len(option.Name)
-					}
-					if option.Description != "" {
-						hasDescriptions = true
-					}
+		return m, nil
+
+	case optionsComputedMsg:
+		// Ignore the options if the input has changed or been cleared since they were requested
+		if m.id.Matches(msg) && m.input == msg.Line {
+			if msg.Err != nil {
+				// FIXME: handle this error somehow?
+				return m, nil
+			}
+			options, directive := msg.Options, msg.Directive
+
+			length := 0
+			hasDescriptions := false
+			for _, option := range options {
+				if len(option.Name) > length {
+					length = len(option.Name)
 				}
+				if option.Description != "" {
+					hasDescriptions = true
+				}
+			}
 
-				m.input = msg.Line
-				m.selectedOption = 0
-				m.options = options
-				m.directive = directive
-				m.longestOption = length
-				m.hasDescriptions = hasDescriptions
-
-				if len(options) == 1 {
-					return m, func() tea.Msg {
-						return SingleAutoCompleteOptionMsg{m.parent}
-					}
+			m.selectedOption = 0
+			m.options = options
+			m.directive = directive
+			m.longestOption = length
+			m.hasDescriptions = hasDescriptions
+
+			if len(options) == 1 {
+				return m, func() tea.Msg {
+					return SingleAutoCompleteOptionMsg{m.parent}
 				}
 			}
 		}
@@ -245,21 +270,90 @@ func (m Model) View() string {
 }
 
 func (m Model) computeOptions(input string) (cobra.ShellCompDirective, []Option, error) {
-	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	ctx, cancel := context.WithTimeout(session.NewContext(context.Background(), m.session), 500*time.Millisecond)
 	defer cancel()
 
-	cmd := fmt.Sprintf("%s %s", cobra.ShellCompRequestCmd, input)
+	args, _ := syntax.CompletionArgs(input)
+	if len(args) == 0 {
+		// Nothing we can complete, such as the target file of a redirect
+		return cobra.ShellCompDirectiveNoFileComp, nil, nil
+	}
+	args = m.expandAliases(args)
+	completeArgs := append([]string{cobra.ShellCompRequestCmd}, args...)
 
 	var sb strings.Builder
 
 	// discard stderr - as cobra autocompletion writes to stderr with "debug" data which we don't care about
-	err := cobrautils.ExecuteCmd(ctx, m.rootCmd, cmd, os.Stdin, &sb, io.Discard)
+	err := m.executor.ExecuteArgs(ctx, completeArgs, os.Stdin, &sb, io.Discard)
 	if err != nil {
 		return cobra.ShellCompDirectiveError, nil, errors.Wrap(err, "failed to execute shell completion")
 	}
 
 	// Grab the output
-	return parseOptions(sb.String())
+	directive, options, err := parseOptions(sb.String())
+	if err != nil || len(args) != 1 {
+		return directive, options, err
+	}
+
+	// If we're completing the command name, aliases are also valid options
+	return directive, m.addAliasOptions(options, args[0]), nil
+}
+
+// expandAliases expands the alias used as the command name in the args, so
+// that the arguments after it are completed for the aliased command
+func (m Model) expandAliases(args []string) []string {
+	if m.session == nil || len(args) < 2 {
+		return args
+	}
+
+	cmd := syntax.Command{Args: make([]syntax.Word, len(args))}
+	for i, arg := range args {
+		cmd.Args[i] = syntax.LiteralWord(arg)
+	}
+
+	cmd, err := cmd.ExpandAliases(m.session.Alias)
+	if err != nil {
+		return args
+	}
+
+	expanded := make([]string, len(cmd.Args))
+	for i, arg := range cmd.Args {
+		expanded[i] = arg.String()
+	}
+	return expanded
+}
+
+// addAliasOptions adds the aliases starting with the prefix to the options
+func (m Model) addAliasOptions(options []Option, prefix string) []Option {
+	if m.session == nil {
+		return options
+	}
+
+	existing := make(map[string]bool, len(options))
+	for _, option := range options {
+		existing[option.Name] = true
+	}
+
+	added := false
+	for _, name := range m.session.AliasNames() {
+		if !strings.HasPrefix(name, prefix) || existing[name] {
+			continue
+		}
+
+		value, _ := m.session.Alias(name)
+		options = append(options, Option{
+			Name:        name,
+			Description: "alias for " + value,
+		})
+		added = true
+	}
+
+	if added {
+		sort.Slice(options, func(i, j int) bool {
+			return options[i].Name < options[j].Name
+		})
+	}
+	return options
 }
 
 // Accept returns the currently selected suggestion, or an empty string
@@ -1,11 +1,11 @@
 package autocomplete
 
 import (
-	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/DomBlack/bubble-shell/internal/syntax"
 	"github.com/spf13/cobra"
 )
 
@@ -47,7 +47,7 @@ func parseOptions(output string) (cobra.ShellCompDirective, []Option, error) {
 		}
 
 		options = append(options, Option{
-			Name:        escapeSpecialCharacters(cmd),
+			Name:        syntax.Quote(cmd),
 			Description: description,
 		})
 	}
@@ -59,18 +59,6 @@ func parseOptions(output string) (cobra.ShellCompDirective, []Option, error) {
 	return directive, options, nil
 }
 
-func escapeSpecialCharacters(val string) string {
-	for _, c := range []string{"\\", "\"", "$", "`", "!"} {
-		val = strings.ReplaceAll(val, c, "\\"+c)
-	}
-
-	if strings.ContainsAny(val, " #&*;<>?[]|~") {
-		val = fmt.Sprintf(`"%s"`, val)
-	}
-
-	return val
-}
-
 func isFlag(arg string) bool {
 	return strings.HasPrefix(arg, "-")
 }
@@ -0,0 +1,272 @@
+package history
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/termemu"
+	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	"github.com/DomBlack/bubble-shell/pkg/tui/errdisplay"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/cockroachdb/errors"
+	"github.com/muesli/termenv"
+)
+
+// ExportFormat is a format the history can be exported to as a transcript
+type ExportFormat string
+
+const (
+	MarkdownFormat ExportFormat = "markdown" // A Markdown document, with the output of each command in a code block
+	HTMLFormat     ExportFormat = "html"     // An HTML page, with each command rendered as it is shown in the shell
+	TextFormat     ExportFormat = "text"     // Plain text without any styling
+)
+
+// exportWidth is the width of the terminal items are rendered for when exported as HTML
+const exportWidth = 100
+
+// ParseExportFormat returns the format with the given name
+func ParseExportFormat(name string) (ExportFormat, error) {
+	switch strings.ToLower(name) {
+	case "markdown", "md":
+		return MarkdownFormat, nil
+	case "html", "htm":
+		return HTMLFormat, nil
+	case "text", "txt":
+		return TextFormat, nil
+	default:
+		return "", errors.Newf("unknown export format %q, expected markdown, html or text", name)
+	}
+}
+
+// ExportFormatForPath returns the format for a file based on its extension,
+// defaulting to plain text if the extension isn't recognised
+func ExportFormatForPath(path string) ExportFormat {
+	format, err := ParseExportFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return TextFormat
+	}
+	return format
+}
+
+// Export writes a transcript of the commands run in this session to w
+//
+// See [ExportItems] for details.
+func (m Model) Export(w io.Writer, format ExportFormat) error {
+	return ExportItems(w, m.cfg, m.Items, format)
+}
+
+// ExportItems writes a transcript of the items to w in the given format, including the
+// prompt, line, time, duration, output and error of each command.
+//
+// Only the commands run in the current session which have finished are included, so items
+// restored from the history file and commands which are still running are left out.
+func ExportItems(w io.Writer, cfg *config.Config, items []Item, format ExportFormat) error {
+	items = transcriptItems(items)
+
+	var transcript string
+	switch format {
+	case MarkdownFormat:
+		transcript = markdownTranscript(cfg, items)
+	case HTMLFormat:
+		transcript = htmlTranscript(cfg, items)
+	case TextFormat:
+		transcript = textTranscript(cfg, items)
+	default:
+		return errors.Newf("unknown export format %q", format)
+	}
+
+	_, err := io.WriteString(w, transcript)
+	return errors.Wrap(err, "unable to write the transcript")
+}
+
+// transcriptItems returns the commands from the items which should be included in a transcript
+func transcriptItems(items []Item) []Item {
+	// Items before the last restored marker are from previous sessions
+	for i := len(items) - 1; i >= 0; i-- {
+		if items[i].ItemType == HistoryRestored {
+			items = items[i+1:]
+			break
+		}
+	}
+
+	transcript := make([]Item, 0, len(items))
+	for _, item := range items {
+		if (item.ItemType == Command || item.ItemType == SubCommand) && item.Status > RunningStatus {
+			item.Folded = false
+			transcript = append(transcript, item)
+		}
+	}
+	return transcript
+}
+
+// exportedLine returns the line of the item with the prompt it was run at
+func exportedLine(item Item) string {
+	if item.ItemType == SubCommand {
+		return "▸ " + item.Line
+	}
+
+	prompt := item.Prompt
+	if prompt == "" {
+		prompt = "> "
+	}
+	return prompt + item.Line
+}
+
+// exportedDetails returns when the item was run, how long it took and if it succeeded
+func exportedDetails(item Item) string {
+	status := "succeeded"
+	if item.Status == ErrorStatus {
+		status = "failed"
+	}
+
+	details := item.Started.Format("2006-01-02 15:04:05")
+	if !item.Finished.IsZero() {
+		details += " · " + formatDuration(item.Finished.Sub(item.Started))
+	}
+	return details + " · " + status
+}
+
+// exportedOutput returns the output of the item without any styling
+func exportedOutput(item Item) string {
+	return termemu.RenderText([]byte(item.Output))
+}
+
+// exportedError returns the error of the item, with its stack trace, without any styling
+func exportedError(cfg *config.Config, item Item) string {
+	plain := *cfg
+	plain.Styles = styles.Plain
+
+	lines := strings.Split(errdisplay.New(&plain, item.Error).View(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func textTranscript(cfg *config.Config, items []Item) string {
+	var sb strings.Builder
+
+	for _, item := range items {
+		fmt.Fprintf(&sb, "%s\n# %s\n", exportedLine(item), exportedDetails(item))
+		for _, redirect := range item.Redirects {
+			fmt.Fprintf(&sb, "↳ %s\n", redirect)
+		}
+		if item.Output != "" {
+			fmt.Fprintln(&sb, exportedOutput(item))
+		}
+		if item.Error != nil {
+			fmt.Fprintln(&sb, exportedError(cfg, item))
+		}
+		sb.WriteString("\n")
+	}
+
+	return sb.String()
+}
+
+func markdownTranscript(cfg *config.Config, items []Item) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "# Shell transcript\n\nExported at %s\n\n", time.Now().Format("2006-01-02 15:04:05"))
+
+	for _, item := range items {
+		heading := "##"
+		if item.ItemType == SubCommand {
+			heading = "###"
+		}
+
+		fmt.Fprintf(&sb, "%s %s\n\n%s\n\n", heading, markdownCode(exportedLine(item)), exportedDetails(item))
+		for _, redirect := range item.Redirects {
+			fmt.Fprintf(&sb, "- %s\n", redirect)
+		}
+		if len(item.Redirects) > 0 {
+			sb.WriteString("\n")
+		}
+		if item.Output != "" {
+			sb.WriteString(markdownCodeBlock(exportedOutput(item)))
+		}
+		if item.Error != nil {
+			sb.WriteString("**Error**\n\n")
+			sb.WriteString(markdownCodeBlock(exportedError(cfg, item)))
+		}
+	}
+
+	return sb.String()
+}
+
+// markdownCode returns the text as inline code, using enough backticks that any within the text are kept
+func markdownCode(text string) string {
+	fence := strings.Repeat("`", longestRun(text, '`')+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		return fence + " " + text + " " + fence
+	}
+	return fence + text + fence
+}
+
+// markdownCodeBlock returns the text as a fenced code block, using a fence longer than any within the text
+func markdownCodeBlock(text string) string {
+	fenceLen := longestRun(text, '`') + 1
+	if fenceLen < 3 {
+		fenceLen = 3
+	}
+	fence := strings.Repeat("`", fenceLen)
+
+	return fence + "text\n" + text + "\n" + fence + "\n\n"
+}
+
+// longestRun returns the length of the longest run of the character in the text
+func longestRun(text string, char rune) int {
+	longest, current := 0, 0
+	for _, r := range text {
+		if r == char {
+			current++
+			if current > longest {
+				longest = current
+			}
+		} else {
+			current = 0
+		}
+	}
+	return longest
+}
+
+// htmlTemplate is the page the items are written into, with the styles
+// for the page using the default colors of a dark terminal
+const htmlTemplate = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Shell transcript</title>
+<style>
+body { background-color: #1d1f21; color: #e0e0e0; }
+pre { font-family: ui-monospace, Menlo, Consolas, monospace; margin: 0; }
+</style>
+</head>
+<body>
+<p><em>Exported at %s</em></p>
+%s
+</body>
+</html>
+`
+
+func htmlTranscript(cfg *config.Config, items []Item) string {
+	// Render the items with colors regardless of the terminal we're running in,
+	// so the styling can be converted into CSS
+	renderer := lipgloss.NewRenderer(io.Discard)
+	renderer.SetColorProfile(termenv.TrueColor)
+	renderer.SetHasDarkBackground(true)
+
+	exportCfg := *cfg
+	exportCfg.Styles = cfg.Styles.WithRenderer(renderer)
+
+	var sb strings.Builder
+	for _, item := range items {
+		view := item.View(&exportCfg, exportWidth)
+		fmt.Fprintf(&sb, "<pre>%s</pre>\n", termemu.RenderHTML([]byte(view)))
+	}
+
+	return fmt.Sprintf(htmlTemplate, html.EscapeString(time.Now().Format("2006-01-02 15:04:05")), sb.String())
+}
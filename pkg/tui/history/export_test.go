@@ -0,0 +1,93 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/cockroachdb/errors"
+)
+
+func TestExportItems(t *testing.T) {
+	restored := NewItem("> ", "old command", SuccessStatus)
+	marker := NewItem("", "restored history from previous session", SuccessStatus)
+	marker.ItemType = HistoryRestored
+
+	echo := NewItem("> ", "echo \"hello\"", SuccessStatus)
+	echo.Output = "\x1b[31mhello\x1b[0m <world>"
+	echo.Finished = echo.Started.Add(1500 * time.Millisecond)
+
+	failed := NewItem("> ", "deploy", ErrorStatus)
+	failed.Error = errors.New("deploy failed")
+	failed.Folded = true
+
+	running := NewItem("> ", "tail", RunningStatus)
+
+	items := []Item{restored, marker, echo, failed, running}
+
+	tests := []struct {
+		format      ExportFormat
+		contains    []string
+		notContains []string
+	}{
+		{
+			format:      TextFormat,
+			contains:    []string{"> echo \"hello\"\n", "1.5s · succeeded", "hello <world>\n", "> deploy", "failed", "deploy failed"},
+			notContains: []string{"old command", "tail", "\x1b["},
+		},
+		{
+			format:      MarkdownFormat,
+			contains:    []string{"# Shell transcript", "## `> echo \"hello\"`", "```text\nhello <world>\n```", "**Error**", "deploy failed"},
+			notContains: []string{"old command", "tail", "\x1b["},
+		},
+		{
+			format:      HTMLFormat,
+			contains:    []string{"<!DOCTYPE html>", "echo &#34;hello&#34;", "hello</span> &lt;world&gt;", "color:", "deploy failed"},
+			notContains: []string{"old command", "tail", "\x1b["},
+		},
+	}
+
+	for _, test := range tests {
+		var sb strings.Builder
+		if err := ExportItems(&sb, config.Default(), items, test.format); err != nil {
+			t.Errorf("%s: unexpected error: %v", test.format, err)
+			continue
+		}
+
+		transcript := sb.String()
+		for _, expected := range test.contains {
+			if !strings.Contains(transcript, expected) {
+				t.Errorf("%s: expected the transcript to contain %q but got:\n%s", test.format, expected, transcript)
+			}
+		}
+		for _, unexpected := range test.notContains {
+			if strings.Contains(transcript, unexpected) {
+				t.Errorf("%s: expected the transcript not to contain %q but got:\n%s", test.format, unexpected, transcript)
+			}
+		}
+	}
+
+	if err := ExportItems(&strings.Builder{}, config.Default(), items, "pdf"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+}
+
+func TestExportFormatForPath(t *testing.T) {
+	tests := []struct {
+		path     string
+		expected ExportFormat
+	}{
+		{path: "session.md", expected: MarkdownFormat},
+		{path: "out/session.HTML", expected: HTMLFormat},
+		{path: "session.txt", expected: TextFormat},
+		{path: "session", expected: TextFormat},
+		{path: "session.log", expected: TextFormat},
+	}
+
+	for _, test := range tests {
+		if got := ExportFormatForPath(test.path); got != test.expected {
+			t.Errorf("ExportFormatForPath(%q): expected %q but got %q", test.path, test.expected, got)
+		}
+	}
+}
@@ -34,7 +34,7 @@ func (m Model) ReadHistory() tea.Cmd {
 		}
 
 		// Get the path to the history file
-		historyFileLocation, err := getHistoryFileLocation(m.cfg.HistoryFile)
+		historyFileLocation, err := FileLocation(m.cfg.HistoryFile)
 		if err != nil {
 			return nil, errors.Wrap(err, "unable to get history file location")
 		}
@@ -125,7 +125,7 @@ func (m Model) SaveHistory(items []Item) tea.Cmd {
 		}
 
 		// Get the path to the history file
-		historyFileLocation, err := getHistoryFileLocation(m.cfg.HistoryFile)
+		historyFileLocation, err := FileLocation(m.cfg.HistoryFile)
 		if err != nil {
 			return errors.Wrap(err, "unable to get history file location")
 		}
@@ -170,8 +170,9 @@ func (m Model) SaveHistory(items []Item) tea.Cmd {
 	}
 }
 
-// getHistoryFileLocation returns the location of the history file
-func getHistoryFileLocation(historyFilename string) (string, error) {
+// FileLocation returns the location of the history file, creating
+// the directory it will be stored in if it does not exist
+func FileLocation(historyFilename string) (string, error) {
 	if ext := filepath.Ext(historyFilename); ext == "" {
 		// we use .jsonl as we save each line as a json object
 		historyFilename += ".jsonl"
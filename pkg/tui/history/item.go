@@ -6,6 +6,8 @@ import (
 	"time"
 
 	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/termemu"
+	"github.com/DomBlack/bubble-shell/pkg/progress"
 	"github.com/DomBlack/bubble-shell/pkg/tui/errdisplay"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -27,6 +29,7 @@ const (
 	Command         ItemType = iota // A command entered by the user
 	InternalError                   // An internal error encountered by the shell
 	HistoryRestored                 // A message indicating that the history was restored from disk and everything above it is from a previous session
+	SubCommand                      // One of the pipelines run from the preceding Command item, when that line contained multiple pipelines
 )
 
 // Item represents a single entry in the command history list
@@ -43,12 +46,71 @@ type Item struct {
 	Status   Status    `json:"status"`   // The status of the command
 	Output   string    `json:"output"`   // The output of the command
 
+	Redirects []Redirection `json:"redirects,omitempty"` // Any streams of the command which were redirected to or from files
+
 	// This group of fields are not serialized and are only used
 	// for rendering the UI during the current shell session
 	StreamedOutput []byte   `json:"-"` // The output of the command as it is streamed
 	ItemType       ItemType `json:"-"` // If true then this item is an internal error item and not a user command
 	Error          error    `json:"-"` // The error returned from the command
 	LoadedHistory  bool     `json:"-"` // If true then this item is a history restored item and not a user command
+	Job            int      `json:"-"` // If non-zero, the number of the background job running this item
+	Folded         bool     `json:"-"` // If true then only a summary of the item is shown, hiding its output and error
+
+	Progress *progress.Group `json:"-"` // The progress reported by the command while it is running
+
+	terminal *termemu.Screen // The streamed output as it would be shown by a terminal
+}
+
+// Redirection records that a stream of a command was redirected to or from a file
+type Redirection struct {
+	Stream string `json:"stream"`           // The name of the stream redirected (stdin, stdout or stderr)
+	Path   string `json:"path"`             // The path of the file
+	Append bool   `json:"append,omitempty"` // If the output was appended to the file
+	Bytes  int64  `json:"bytes"`            // The number of bytes read from or written to the file
+}
+
+// String returns a short summary of the redirection
+func (r Redirection) String() string {
+	switch {
+	case r.Stream == "stdin":
+		return fmt.Sprintf("%s read from %s (%s)", r.Stream, r.Path, formatBytes(r.Bytes))
+	case r.Append:
+		return fmt.Sprintf("%s appended to %s (%s)", r.Stream, r.Path, formatBytes(r.Bytes))
+	default:
+		return fmt.Sprintf("%s written to %s (%s)", r.Stream, r.Path, formatBytes(r.Bytes))
+	}
+}
+
+// formatBytes formats the number of bytes in a human-readable form
+func formatBytes(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration formats how long a command took in a short human-readable form
+func formatDuration(dur time.Duration) string {
+	switch {
+	case dur < 1*time.Second:
+		return fmt.Sprintf("%dms", dur.Milliseconds())
+	case dur < 10*time.Second:
+		return fmt.Sprintf("%.1fs", dur.Seconds())
+	case dur < 1*time.Minute:
+		return fmt.Sprintf("%.0fs", dur.Seconds())
+	case dur < 2*time.Minute:
+		return fmt.Sprintf("%.1fm", dur.Minutes())
+	default:
+		return fmt.Sprintf("%.0fm", dur.Minutes())
+	}
 }
 
 // NewItem creates a new history item with the given line and status
@@ -63,6 +125,20 @@ func NewItem(prompt, line string, status Status) Item {
 	}
 }
 
+// statusSymbol returns a symbol representing the status of the item
+func (i Item) statusSymbol() string {
+	switch i.Status {
+	case RunningStatus:
+		return "…"
+	case SuccessStatus:
+		return "✔"
+	case ErrorStatus:
+		return "✘"
+	default:
+		return "?"
+	}
+}
+
 // Init implements tea.Model init function
 func (i Item) Init() tea.Cmd {
 	return nil
@@ -91,19 +167,11 @@ func (i Item) View(cfg *config.Config, width int) string {
 		finished = time.Now()
 	}
 	if !finished.IsZero() {
-		dur := finished.Sub(i.Started)
-		switch {
-		case dur < 1*time.Second:
-			timeStr = fmt.Sprintf("(%dms) %s", dur.Milliseconds(), timeStr)
-		case dur < 10*time.Second:
-			timeStr = fmt.Sprintf("(%.1fs) %s", dur.Seconds(), timeStr)
-		case dur < 1*time.Minute:
-			timeStr = fmt.Sprintf("(%.0fs) %s", dur.Seconds(), timeStr)
-		case dur < 2*time.Minute:
-			timeStr = fmt.Sprintf("(%.1fm) %s", dur.Minutes(), timeStr)
-		default:
-			timeStr = fmt.Sprintf("(%.0fm) %s", dur.Minutes(), timeStr)
-		}
+		timeStr = fmt.Sprintf("(%s) %s", formatDuration(finished.Sub(i.Started)), timeStr)
+	}
+
+	if i.Job != 0 {
+		timeStr = fmt.Sprintf("[%d] %s", i.Job, timeStr)
 	}
 
 	// Render the input line
@@ -117,6 +185,9 @@ func (i Item) View(cfg *config.Config, width int) string {
 
 		lines[0] = cfg.Styles.HistoricPrompt.Render(prompt) + lines[0]
 
+	case SubCommand:
+		lines[0] = cfg.Styles.HistoricPrompt.Render("  ▸ ") + lines[0]
+
 	case InternalError:
 		lines[0] = cfg.Styles.InternalError.Render("!! ") + lines[0]
 
@@ -125,19 +196,38 @@ func (i Item) View(cfg *config.Config, width int) string {
 		lines[0] += cfg.Styles.HistoricLine.Render(strings.Repeat("-", width-lipgloss.Width(lines[0])))
 	}
 
-	if i.ItemType != HistoryRestored {
-		// Add the time to the line
-		lineWidth := lipgloss.Width(lines[0])
-		timeStr = cfg.Styles.HistoricTime.Copy().Width(width - lineWidth).Render(timeStr)
-		lines[0] = lipgloss.JoinHorizontal(lipgloss.Left, lines[0], timeStr)
+	// Add the time to the line
+	addTime := func(line string) string {
+		if i.ItemType == HistoryRestored {
+			return line
+		}
+
+		lineWidth := lipgloss.Width(line)
+		return lipgloss.JoinHorizontal(lipgloss.Left, line, cfg.Styles.HistoricTime.Copy().Width(width-lineWidth).Render(timeStr))
+	}
+	header := lines[0]
+	lines[0] = addTime(header)
+
+	// Summarise any redirections, as the output went to those files
+	for _, redirect := range i.Redirects {
+		lines = append(lines, cfg.Styles.HistoricLine.Render("↳ "+redirect.String()))
 	}
 
 	// Render the output if we have any
 	if i.Output != "" {
 		lines = append(lines, i.Output)
 	} else if len(i.StreamedOutput) > 0 && i.Status == RunningStatus {
-		// If we're running, then render the streamed output
-		lines = append(lines, strings.TrimSpace(string(i.StreamedOutput)))
+		// If we're running, then render the streamed output as a terminal would
+		if i.terminal != nil {
+			lines = append(lines, strings.TrimSpace(i.terminal.String()))
+		} else {
+			lines = append(lines, strings.TrimSpace(termemu.Render(i.StreamedOutput)))
+		}
+	}
+
+	// Render the progress of the command while it's running
+	if i.Status == RunningStatus {
+		lines = append(lines, renderProgress(cfg, width, i.Progress.States())...)
 	}
 
 	// Render the error if we have any
@@ -148,8 +238,25 @@ func (i Item) View(cfg *config.Config, width int) string {
 		}
 	}
 
-	// Add a spacer line between items
-	lines = append(lines, "")
+	// A folded item is summarised on a single line, with the status of the command
+	if i.Folded {
+		hidden := 0
+		if len(lines) > 1 {
+			hidden = lipgloss.Height(lipgloss.JoinVertical(lipgloss.Left, lines[1:]...))
+		}
+
+		summary := fmt.Sprintf("  %s %d lines folded", i.statusSymbol(), hidden)
+		if hidden == 1 {
+			summary = fmt.Sprintf("  %s 1 line folded", i.statusSymbol())
+		}
+		lines = []string{addTime(header + cfg.Styles.FoldedSummary.Render(summary))}
+	}
+
+	// Add a spacer line between items, sub commands are kept
+	// together with their siblings unless they have output
+	if i.ItemType != SubCommand || len(lines) > 1 {
+		lines = append(lines, "")
+	}
 
 	// Now join all the lines together
 	return lipgloss.JoinVertical(lipgloss.Left, lines...)
@@ -1,10 +1,12 @@
 package history
 
 import (
+	"fmt"
 	"strings"
 	"time"
 
 	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/termemu"
 	. "github.com/DomBlack/bubble-shell/pkg/modelid"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -22,8 +24,10 @@ type Model struct {
 	cfg           *config.Config // The config for this shell
 	width, height int            // The width and height of the space we're given to render in
 
-	Scrollback int    // The number of lines to scroll back
-	Items      []Item // The history items we're currently displaying
+	Scrollback  int    // The number of lines to scroll back
+	scrollTotal int    // The total number of lines in the history when Scrollback was last set
+	selected    xid.ID // The ID of the item the user has selected, if any
+	Items       []Item // The history items we're currently displaying
 }
 
 // New creates a new history model
@@ -89,39 +93,78 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		if m.id.Matches(msg) {
 			var cmds []tea.Cmd
 
-			// If we're an inline shell and the item is no longer running
-			// then we need to print it outside the bubbletea managed area
-			// so the normal shell scrollbar will work
-			if m.cfg.InlineShell && msg.Item.Status > RunningStatus {
-				// Mark it as loaded history so we wont render it within the bubble tea program now
-				msg.Item.LoadedHistory = true
-				cmds = append(cmds, tea.Println(strings.TrimSuffix(msg.Item.View(m.cfg, m.width), "\n")))
-			}
-
 			// Start searching from the end of the slice as
 			// 99 times out of 100 we'll be updating the most
 			// recent item
-			found := false
+			foundIdx := -1
 			if len(m.Items) > 0 {
 				for i := len(m.Items) - 1; i >= 0; i-- {
 					if m.Items[i].ID == msg.Item.ID {
+						msg.Item.Folded = m.Items[i].Folded
 						m.Items[i] = msg.Item
-						found = true
+						foundIdx = i
 						break
 					}
 				}
 			}
 
 			// If we didn't find it then we need to add it
-			if !found {
-				cmds = append(cmds, m.AppendItem(msg.Item))
-			} else {
-				cmds = append(cmds, m.SaveHistory(m.Items))
+			if foundIdx == -1 {
+				return m, m.AppendItem(msg.Item)
+			}
+
+			// If we're an inline shell and the item is no longer running
+			// then we need to print it outside the bubbletea managed area
+			// so the normal shell scrollbar will work.
+			//
+			// Sub commands are printed alongside their parent once it has finished, apart from
+			// background jobs which may still be running, so are printed once they finish
+			if m.cfg.InlineShell && msg.Item.Status > RunningStatus && (msg.Item.ItemType != SubCommand || msg.Item.Job != 0) {
+				views := []string{msg.Item.View(m.cfg, m.width)}
+				m.Items[foundIdx].LoadedHistory = true
+
+				if msg.Item.ItemType != SubCommand {
+					for i := foundIdx + 1; i < len(m.Items) && m.Items[i].ItemType == SubCommand; i++ {
+						if m.Items[i].Status > RunningStatus && !m.Items[i].LoadedHistory {
+							views = append(views, m.Items[i].View(m.cfg, m.width))
+
+							// Mark them as loaded history so we wont render them within the bubble tea program now
+							m.Items[i].LoadedHistory = true
+						}
+					}
+				}
+
+				cmds = append(cmds, tea.Println(strings.TrimSuffix(lipgloss.JoinVertical(lipgloss.Left, views...), "\n")))
 			}
 
+			cmds = append(cmds, m.SaveHistory(m.Items))
 			return m, tea.Batch(cmds...)
 		}
 
+	// Removing an item from the history also removes the
+	// sub commands which were run from it
+	case removeItemMsg:
+		if m.id.Matches(msg) {
+			if start, end, found := m.itemRange(msg.ItemID); found && !m.IsRunning(msg.ItemID) {
+				m.Items = append(m.Items[:start:start], m.Items[end:]...)
+				return m, m.SaveHistory(m.Items)
+			}
+		}
+
+	case clearMsg:
+		if m.id.Matches(msg) {
+			var running []Item
+			for _, item := range m.Items {
+				if item.Status == RunningStatus {
+					running = append(running, item)
+				}
+			}
+
+			m.Items = running
+			m.ScrollToBottom()
+			return m, m.SaveHistory(m.Items)
+		}
+
 	case streamOutput:
 		if m.id.Matches(msg) {
 			// Start searching from the end of the slice as
@@ -131,6 +174,11 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 				for i := len(m.Items) - 1; i >= 0; i-- {
 					if m.Items[i].ID == msg.ItemID {
 						m.Items[i].StreamedOutput = append(m.Items[i].StreamedOutput, msg.Bytes...)
+
+						if m.Items[i].terminal == nil {
+							m.Items[i].terminal = &termemu.Screen{}
+						}
+						_, _ = m.Items[i].terminal.Write(msg.Bytes)
 						break
 					}
 				}
@@ -138,14 +186,22 @@ func (m Model) Update(msg tea.Msg) (Model, tea.Cmd) {
 		}
 
 	case tickMsg:
-		if m.id.Matches(msg) && len(m.Items) > 0 {
-			// Tick messages are so we can update the timer when the item is running
-			lastItem := m.Items[len(m.Items)-1]
-			if lastItem.ID == msg.ItemID && lastItem.Status == RunningStatus {
+		if m.id.Matches(msg) {
+			// Tick messages are so we can update the timer when the item is running,
+			// which may not be the last item if it's running as a background job
+			for i := len(m.Items) - 1; i >= 0; i-- {
+				item := m.Items[i]
+				if item.ID != msg.ItemID {
+					continue
+				}
+				if item.Status != RunningStatus {
+					break
+				}
+
 				dur := 10 * time.Millisecond
-				if time.Since(lastItem.Started).Seconds() > 1 {
+				if time.Since(item.Started).Seconds() > 1 {
 					dur = 100 * time.Millisecond
-				} else if time.Since(lastItem.Started).Seconds() > 10 {
+				} else if time.Since(item.Started).Seconds() > 10 {
 					// under 10 seconds
 					dur = time.Second
 				}
@@ -179,15 +235,18 @@ func (m Model) View() string {
 		return lipgloss.JoinVertical(lipgloss.Left, lines...)
 	}
 
+	// While scrolled back, output added since keeps the same lines in view
+	// rather than moving them up the screen
+	scrollback, newLines := m.scrollback()
+
 	// Render the lines in reverse order
 	// But we only want a maximum of m.height lines
 	lineCount := 0
-	getLines := m.height + m.Scrollback
+	getLines := m.height + scrollback
 	lines := make([]string, getLines)
 renderLoop:
 	for i := len(m.Items) - 1; i >= 0; i-- {
-		item := lineRender.Render(m.Items[i].View(m.cfg, m.width))
-		itemLines := strings.Split(item, "\n")
+		itemLines := strings.Split(m.renderItem(m.Items[i]), "\n")
 
 		for j := len(itemLines) - 1; j >= 0; j-- {
 			lines[lineCount] = itemLines[j]
@@ -203,11 +262,22 @@ renderLoop:
 		lines[i], lines[j] = lines[j], lines[i]
 	}
 
-	// Only keep the last m.height lines
+	// Drop the lines below the ones scrolled back to, and only keep the last m.height lines
+	lines = lines[:len(lines)-scrollback]
 	if len(lines) > m.height {
 		lines = lines[len(lines)-m.height:]
 	}
 
+	if scrollback > 0 && len(lines) > 0 {
+		indicator := fmt.Sprintf(" scrolled back %d lines", scrollback)
+		if newLines > 0 {
+			indicator += fmt.Sprintf(", %d new lines below", newLines)
+		}
+		indicator += fmt.Sprintf(" (%s to return) ", m.cfg.KeyMap.End.Help().Key)
+
+		lines[len(lines)-1] = m.cfg.Styles.ScrollIndicator.Render(indicator)
+	}
+
 	// Join all the lines together
 	output := lipgloss.JoinVertical(lipgloss.Left, lines...)
 
@@ -215,6 +285,180 @@ renderLoop:
 	return lipgloss.NewStyle().Height(m.height).Render(output)
 }
 
+// ScrollBy scrolls the history back by the given number of lines,
+// or forward towards the most recent output if lines is negative
+func (m *Model) ScrollBy(lines int) {
+	total := m.lineCount()
+	scrollback, _ := m.scrollback()
+
+	scrollback += lines
+	if scrollback > total-m.height {
+		scrollback = total - m.height
+	}
+	if scrollback < 0 {
+		scrollback = 0
+	}
+
+	m.Scrollback = scrollback
+	m.scrollTotal = total
+}
+
+// ScrollToTop scrolls the history back to the oldest item
+func (m *Model) ScrollToTop() {
+	m.ScrollBy(m.lineCount())
+}
+
+// ScrollToBottom scrolls the history to the most recent output
+func (m *Model) ScrollToBottom() {
+	m.Scrollback = 0
+}
+
+// IsScrolledBack returns true if the history is not showing the most recent output
+func (m Model) IsScrolledBack() bool {
+	return m.Scrollback > 0
+}
+
+// Height returns the number of lines the history is rendered in
+func (m Model) Height() int {
+	return m.height
+}
+
+// scrollback returns the number of lines the history is scrolled back by, which includes any
+// lines added since the user scrolled back, and the number of those new lines
+func (m Model) scrollback() (scrollback int, newLines int) {
+	if m.Scrollback <= 0 {
+		return 0, 0
+	}
+
+	total := m.lineCount()
+	newLines = total - m.scrollTotal
+	if newLines < 0 {
+		newLines = 0
+	}
+
+	scrollback = m.Scrollback + newLines
+	if scrollback > total-m.height {
+		scrollback = total - m.height
+	}
+	if scrollback < 0 {
+		scrollback = 0
+	}
+
+	return scrollback, newLines
+}
+
+// lineCount returns the number of lines all the history items take up when rendered
+func (m Model) lineCount() int {
+	count := 0
+	for _, item := range m.Items {
+		count += lipgloss.Height(m.renderItem(item))
+	}
+	return count
+}
+
+// renderItem renders the item to fill the width of the history, marking it if it is selected
+func (m Model) renderItem(item Item) string {
+	lineRender := lipgloss.NewStyle().Width(m.width)
+
+	if !m.selected.IsNil() && item.ID == m.selected {
+		style := m.cfg.Styles.SelectedItem
+		return lineRender.Render(style.Render(item.View(m.cfg, m.width-style.GetHorizontalFrameSize())))
+	}
+
+	return lineRender.Render(item.View(m.cfg, m.width))
+}
+
+// Select marks the item with the given ID as selected, scrolling
+// the history so the item can be seen
+func (m *Model) Select(id xid.ID) {
+	m.selected = id
+
+	// Find how many lines are below the item
+	below, itemLines := 0, -1
+	for i := len(m.Items) - 1; i >= 0; i-- {
+		lines := lipgloss.Height(m.renderItem(m.Items[i]))
+		if m.Items[i].ID == id {
+			itemLines = lines
+			break
+		}
+		below += lines
+	}
+	if itemLines < 0 {
+		return
+	}
+
+	current, _ := m.scrollback()
+	target := current
+	switch {
+	case below < current:
+		target = below
+	case below+itemLines > current+m.height:
+		target = below + itemLines - m.height
+	}
+	m.ScrollBy(target - current)
+}
+
+// ClearSelection removes the selection from the item which was selected
+func (m *Model) ClearSelection() {
+	m.selected = xid.NilID()
+}
+
+// SelectedItem returns the item which is selected, if there is one
+func (m Model) SelectedItem() (Item, bool) {
+	if m.selected.IsNil() {
+		return Item{}, false
+	}
+
+	for i := len(m.Items) - 1; i >= 0; i-- {
+		if m.Items[i].ID == m.selected {
+			return m.Items[i], true
+		}
+	}
+	return Item{}, false
+}
+
+// IsRunning reports whether the item with the given ID, or any of the sub commands
+// which were run from it, is still running
+func (m Model) IsRunning(id xid.ID) bool {
+	start, end, _ := m.itemRange(id)
+	for _, item := range m.Items[start:end] {
+		if item.Status == RunningStatus {
+			return true
+		}
+	}
+	return false
+}
+
+// itemRange returns the range of the items for the item with the given ID, which
+// includes any sub commands which were run from it
+func (m Model) itemRange(id xid.ID) (start int, end int, found bool) {
+	for i, item := range m.Items {
+		if item.ID != id {
+			continue
+		}
+
+		end := i + 1
+		if item.ItemType != SubCommand {
+			for end < len(m.Items) && m.Items[end].ItemType == SubCommand {
+				end++
+			}
+		}
+		return i, end, true
+	}
+	return 0, 0, false
+}
+
+// ToggleFolded folds the item with the given ID if it is unfolded, or unfolds it
+// if it is folded. A folded item is shown as a single line summarising it.
+func (m *Model) ToggleFolded(id xid.ID) {
+	for i := len(m.Items) - 1; i >= 0; i-- {
+		if m.Items[i].ID == id {
+			m.Items[i].Folded = !m.Items[i].Folded
+			return
+		}
+	}
+}
+
 // Lookback returns the item that is lookback items back in the history
 //
 // Valid range for lookback is 1 to len(history.Items)
@@ -287,6 +531,39 @@ func (m Model) UpdateItem(item Item) tea.Cmd {
 	}
 }
 
+// RemoveItem removes an item from the history, along with any sub commands
+// which were run from it. Items which are still running are not removed, as
+// they would be added back the next time their command updates them.
+func (m Model) RemoveItem(id xid.ID) tea.Cmd {
+	return func() tea.Msg {
+		return removeItemMsg{
+			ID:     m.id,
+			ItemID: id,
+		}
+	}
+}
+
+// Clear removes all the items from the history, apart from the items of any commands still running
+func (m Model) Clear() tea.Cmd {
+	return func() tea.Msg {
+		return clearMsg{ID: m.id}
+	}
+}
+
+// Commands returns the commands the user has run from the items, oldest first.
+//
+// These are numbered from 1 by the `history` command, and can be
+// referred to by those numbers with history expansion, such as `!42`.
+func Commands(items []Item) []Item {
+	var commands []Item
+	for _, item := range items {
+		if item.ItemType == Command {
+			commands = append(commands, item)
+		}
+	}
+	return commands
+}
+
 // StreamOutputFor returns a function that appends the given bytes to the given item
 // by returning an append message
 func (m Model) StreamOutputFor(cmd Item) func(bytes []byte) tea.Msg {
@@ -318,6 +595,23 @@ func (msg updateItemMsg) ForModelID() ID {
 	return msg.ID
 }
 
+type removeItemMsg struct {
+	ID     ID
+	ItemID xid.ID
+}
+
+func (msg removeItemMsg) ForModelID() ID {
+	return msg.ID
+}
+
+type clearMsg struct {
+	ID ID
+}
+
+func (msg clearMsg) ForModelID() ID {
+	return msg.ID
+}
+
 type streamOutput struct {
 	ID     ID
 	ItemID xid.ID
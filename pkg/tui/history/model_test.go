@@ -0,0 +1,116 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+)
+
+// outputItem returns a finished item with the given number of lines of output
+func outputItem(name string, lines int) Item {
+	output := make([]string, lines)
+	for i := range output {
+		output[i] = fmt.Sprintf("%s %d", name, i)
+	}
+
+	item := NewItem("> ", name, SuccessStatus)
+	item.Output = strings.Join(output, "\n")
+	return item
+}
+
+func TestScrollback(t *testing.T) {
+	cfg := config.Default()
+	cfg.Styles = styles.Plain
+
+	m := New(cfg)
+	m.width, m.height = 40, 5
+	m.Items = []Item{outputItem("first", 20)}
+
+	bottom := m.View()
+	if !strings.Contains(bottom, "first 19") {
+		t.Fatalf("expected the most recent output to be shown but got:\n%s", bottom)
+	}
+
+	m.ScrollBy(10)
+	if !m.IsScrolledBack() {
+		t.Fatalf("expected the history to be scrolled back")
+	}
+	scrolled := m.View()
+	if !strings.Contains(scrolled, "first 8") || strings.Contains(scrolled, "first 19") {
+		t.Errorf("expected the view to be scrolled back 10 lines but got:\n%s", scrolled)
+	}
+
+	// New output should not move the lines being shown
+	m.Items = append(m.Items, outputItem("second", 3))
+	withNew := m.View()
+	if !strings.Contains(withNew, "first 8") || !strings.Contains(withNew, "new lines below") {
+		t.Errorf("expected the view to stay scrolled back with new lines below but got:\n%s", withNew)
+	}
+
+	// Scrolling is limited to the lines in the history
+	m.ScrollToTop()
+	if top := m.View(); !strings.Contains(top, "first 0") {
+		t.Errorf("expected the oldest output to be shown but got:\n%s", top)
+	}
+	m.ScrollBy(-1000)
+	if m.IsScrolledBack() {
+		t.Errorf("expected scrolling forward past the most recent output to stop at the bottom")
+	}
+}
+
+func TestFoldedItem(t *testing.T) {
+	cfg := config.Default()
+	cfg.Styles = styles.Plain
+
+	item := outputItem("first", 3)
+	item.Folded = true
+
+	view := item.View(cfg, 60)
+	if strings.Contains(view, "first 0") || !strings.Contains(view, "✔ 3 lines folded") {
+		t.Errorf("expected the output to be summarised but got:\n%s", view)
+	}
+	if lines := strings.Split(strings.TrimSpace(view), "\n"); len(lines) != 1 {
+		t.Errorf("expected a folded item to be a single line but got %d lines", len(lines))
+	}
+}
+
+func TestRemoveItem(t *testing.T) {
+	m := New(config.Default())
+
+	parent := outputItem("parent", 1)
+	child := outputItem("child", 1)
+	child.ItemType = SubCommand
+	last := outputItem("last", 1)
+	m.Items = []Item{parent, child, last}
+
+	m, _ = m.Update(removeItemMsg{ID: m.id, ItemID: parent.ID})
+	if len(m.Items) != 1 || m.Items[0].ID != last.ID {
+		t.Errorf("expected the item and its sub commands to be removed but got %d items", len(m.Items))
+	}
+
+	// Items are kept while they, or any of their sub commands, are running
+	running := NewItem("> ", "deploy &", SuccessStatus)
+	job := NewItem("", "deploy", RunningStatus)
+	job.ItemType = SubCommand
+	m.Items = []Item{running, job}
+
+	m, _ = m.Update(removeItemMsg{ID: m.id, ItemID: running.ID})
+	if len(m.Items) != 2 {
+		t.Errorf("expected the running item to be kept but got %d items", len(m.Items))
+	}
+}
+
+func TestClear(t *testing.T) {
+	m := New(config.Default())
+
+	running := NewItem("> ", "history -c", RunningStatus)
+	m.Items = []Item{outputItem("first", 1), outputItem("second", 1), running}
+
+	m, _ = m.Update(clearMsg{ID: m.id})
+	if len(m.Items) != 1 || m.Items[0].ID != running.ID {
+		t.Errorf("expected only the running item to be kept but got %d items", len(m.Items))
+	}
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/progress"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxProgressBarWidth is the maximum width of the bar part of a progress bar
+const maxProgressBarWidth = 40
+
+// progressSpinner is the spinner shown for operations where the total size is not known
+var progressSpinner = spinner.Dot
+
+// renderProgress renders the state of each of the bars as a single line
+func renderProgress(cfg *config.Config, width int, states []progress.State) []string {
+	lines := make([]string, 0, len(states))
+
+	for _, state := range states {
+		label := cfg.Styles.ProgressLabel.Render(state.Label)
+		percent := state.Percent()
+
+		switch {
+		case state.Done:
+			lines = append(lines, cfg.Styles.ProgressSpinner.Render("✓")+" "+label)
+
+		case percent < 0:
+			// The size isn't known, so show a spinner based on how long the operation has been running
+			frame := int(time.Since(state.Started)/progressSpinner.FPS) % len(progressSpinner.Frames)
+			line := cfg.Styles.ProgressSpinner.Render(strings.TrimSpace(progressSpinner.Frames[frame])) + " " + label
+			if state.Current > 0 {
+				line += cfg.Styles.ProgressLabel.Render(fmt.Sprintf(" (%d)", state.Current))
+			}
+			lines = append(lines, line)
+
+		default:
+			suffix := cfg.Styles.ProgressLabel.Render(fmt.Sprintf(" %3.0f%% (%d/%d)", percent*100, state.Current, state.Total))
+
+			barWidth := width - lipgloss.Width(label) - lipgloss.Width(suffix) - 1
+			if barWidth > maxProgressBarWidth {
+				barWidth = maxProgressBarWidth
+			}
+			if barWidth < 0 {
+				barWidth = 0
+			}
+
+			filled := int(percent * float64(barWidth))
+			bar := cfg.Styles.ProgressBar.Render(strings.Repeat("█", filled)) +
+				cfg.Styles.ProgressBarEmpty.Render(strings.Repeat("░", barWidth-filled))
+
+			lines = append(lines, label+" "+bar+suffix)
+		}
+	}
+
+	return lines
+}
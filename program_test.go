@@ -0,0 +1,68 @@
+package shell
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// runShell runs the shell as a program, passing each model the program sees to check, and
+// once the shell is waiting for a command runs steps before quitting and returning the final model
+func runShell(t *testing.T, m tea.Model, check func(m Model), steps func(p *tea.Program)) Model {
+	t.Helper()
+
+	ready := make(chan struct{})
+	isReady := false
+	p := tea.NewProgram(m,
+		tea.WithInput(nil),
+		tea.WithOutput(io.Discard),
+		tea.WithFilter(func(model tea.Model, msg tea.Msg) tea.Msg {
+			m := model.(Model)
+			if _, ok := m.mode.(*CommandEntryMode); ok && !isReady {
+				isReady = true
+				close(ready)
+			}
+
+			check(m)
+			return msg
+		}),
+	)
+
+	done := make(chan tea.Model)
+	go func() {
+		final, err := p.Run()
+		if err != nil {
+			t.Errorf("expected the shell to run but got %v", err)
+		}
+		done <- final
+	}()
+
+	p.Send(tea.WindowSizeMsg{Width: 100, Height: 40})
+	if wait(t, ready, "the shell to be ready") {
+		steps(p)
+	}
+	p.Quit()
+
+	return (<-done).(Model)
+}
+
+// enterLine types the line into the shell and executes it
+func enterLine(p *tea.Program, line string) {
+	p.Send(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(line)})
+	p.Send(tea.KeyMsg{Type: tea.KeyEnter})
+}
+
+// wait waits for the channel to be closed, failing the test if it takes too long
+func wait(t *testing.T, ch <-chan struct{}, what string) bool {
+	t.Helper()
+
+	select {
+	case <-ch:
+		return true
+	case <-time.After(5 * time.Second):
+		t.Errorf("expected %s but timed out", what)
+		return false
+	}
+}
@@ -0,0 +1,116 @@
+package shell
+
+import (
+	"os"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/errors"
+)
+
+// recording records everything the shell renders into the file set with [WithRecording]
+//
+// As bubbletea only redraws the parts of the screen which have changed, each frame the
+// shell renders is recorded as a redraw of the whole screen, so the recording plays back
+// the same no matter where it is played.
+//
+// It is only used from within the bubbletea update loop, so needs no locking.
+type recording struct {
+	path     string
+	file     *os.File
+	recorder *asciicast.Recorder
+	height   int
+
+	lastFrame string
+	failed    bool // Set if the recording could not be written, after which nothing more is recorded
+}
+
+// newRecording returns a recording to the file at path, or nil if path is empty
+func newRecording(path string) *recording {
+	if path == "" {
+		return nil
+	}
+	return &recording{path: path}
+}
+
+// resize records the size of the screen changing, starting the recording with
+// the first size as the recording can't be started without knowing the size of the screen.
+func (r *recording) resize(m Model, width, height int) tea.Cmd {
+	if r == nil || r.failed {
+		return nil
+	}
+	r.height = height
+
+	if r.recorder == nil {
+		return r.fail(m, r.start(width, height))
+	}
+	return r.fail(m, r.recorder.Resize(width, height))
+}
+
+// start creates the recording file and writes the header to it
+func (r *recording) start(width, height int) error {
+	file, err := os.Create(r.path)
+	if err != nil {
+		return errors.Wrap(err, "unable to create the recording file")
+	}
+
+	r.recorder, err = asciicast.NewRecorder(file, asciicast.Header{
+		Width:  width,
+		Height: height,
+		Env: map[string]string{
+			"TERM":  os.Getenv("TERM"),
+			"SHELL": os.Getenv("SHELL"),
+		},
+	})
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	r.file = file
+
+	// Hide the cursor, as the shell renders its own
+	return r.recorder.Output("\x1b[?25l\x1b[2J")
+}
+
+// frame records the view rendered by the shell, if it has changed since the last frame
+func (r *recording) frame(view string) {
+	if r == nil || r.recorder == nil || r.failed || view == r.lastFrame {
+		return
+	}
+	r.lastFrame = view
+
+	// Like bubbletea, only the bottom of views taller than the screen are shown
+	lines := strings.Split(view, "\n")
+	if r.height > 0 && len(lines) > r.height {
+		lines = lines[len(lines)-r.height:]
+	}
+
+	// Move to the top of the screen, and then rewrite each line clearing anything left from the last frame
+	if err := r.recorder.Output("\x1b[H" + strings.Join(lines, "\x1b[K\r\n") + "\x1b[K\x1b[J"); err != nil {
+		r.failed = true
+	}
+}
+
+// fail stops the recording if err is set, returning a [tea.Cmd] to show the error in the history
+func (r *recording) fail(m Model, err error) tea.Cmd {
+	if err == nil {
+		return nil
+	}
+	r.failed = true
+
+	item := history.NewItem("", "error recording the shell", history.ErrorStatus)
+	item.ItemType = history.InternalError
+	item.Error = err
+	return m.history.AppendItem(item)
+}
+
+// close closes the recording file
+func (r *recording) close() {
+	if r != nil && r.file != nil {
+		_ = r.file.Close()
+		r.file = nil
+		r.failed = true
+	}
+}
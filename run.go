@@ -0,0 +1,100 @@
+package shell
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/internal/syntax"
+	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// Run is an entry point for applications which want to use the same binary both
+// interactively and from scripts or CI jobs. It picks how to run based on how the
+// program was started:
+//
+//   - If arguments were given on the command line (such as `my-app deploy api`), they
+//     are run as a single command and then Run returns.
+//   - If stdin is not a terminal (such as `echo "status" | my-app`), each line read from
+//     stdin is run as with [RunScript].
+//   - Otherwise the interactive shell is started with bubbletea.
+//
+// When not running interactively, an error is returned if any of the commands failed
+// (once it has been written to os.Stderr), so the program should exit with a non-zero code:
+//
+//	if err := shell.Run(rootCmd); err != nil {
+//		os.Exit(1)
+//	}
+func Run(rootCmd *cobra.Command, options ...Option) error {
+	return run(rootCmd, os.Args[1:], os.Stdin, os.Stdout, os.Stderr, options...)
+}
+
+// run runs the shell in the mode picked by [Run] for the given
+// command line arguments and standard streams
+func run(rootCmd *cobra.Command, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer, options ...Option) error {
+	cfg := config.Default()
+	interactive := len(args) == 0 && isTerminal(stdin)
+	if !interactive {
+		cfg.Styles = styles.Plain
+	}
+	for _, option := range options {
+		option(cfg)
+	}
+
+	switch {
+	case len(args) > 0:
+		return runArgs(cfg, rootCmd, args, stdin, stdout, stderr)
+
+	case !interactive:
+		return runScript(cfg, rootCmd, stdin, stdout, stderr)
+
+	default:
+		cfg.Output = stdout
+		programOptions := []tea.ProgramOption{tea.WithInput(stdin), tea.WithOutput(stdout)}
+		if !cfg.InlineShell {
+			programOptions = append(programOptions, tea.WithAltScreen(), tea.WithMouseCellMotion())
+		}
+
+		_, err := tea.NewProgram(newModel(cfg, rootCmd), programOptions...).Run()
+		return errors.Wrap(err, "unable to run the shell")
+	}
+}
+
+// runArgs runs the command line arguments as a single command, with each
+// argument passed to the command as is
+func runArgs(cfg *config.Config, rootCmd *cobra.Command, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	executor := newExecutor(cfg, rootCmd)
+
+	s, aliasErr := newSession(cfg)
+	if aliasErr != nil {
+		reportScriptError(cfg, stderr, aliasErr)
+	}
+	if err := loadAliasFile(cfg, s); err != nil {
+		reportScriptError(cfg, stderr, err)
+	}
+
+	// Quote the arguments, so they are not split or expanded again when the line is parsed
+	quoted := make([]string, len(args))
+	for i, arg := range args {
+		quoted[i] = syntax.Quote(arg)
+	}
+
+	ctx := session.NewContext(cfg.RootContext, s)
+	err := executor.RunLine(ctx, strings.Join(quoted, " "), stdin, stdout, stderr)
+	if err != nil {
+		reportScriptError(cfg, stderr, err)
+	}
+	return err
+}
+
+// isTerminal reports whether the reader is a terminal
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	return ok && term.IsTerminal(int(file.Fd()))
+}
@@ -0,0 +1,127 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// testRootCmd returns a command tree with an `echo` command which writes its arguments,
+// a `cat` command which copies its input and a `fail` command which always returns an error
+func testRootCmd() *cobra.Command {
+	rootCmd := &cobra.Command{}
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "echo",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := fmt.Fprintln(cmd.OutOrStdout(), strings.Join(args, ","))
+			return err
+		},
+	})
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "cat",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			_, err := io.Copy(cmd.OutOrStdout(), cmd.InOrStdin())
+			return err
+		},
+	})
+	rootCmd.AddCommand(&cobra.Command{
+		Use: "fail",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return errors.New("failed")
+		},
+	})
+	return rootCmd
+}
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		stdin    string
+		expected string
+		failed   bool
+	}{
+		{
+			name:     "runs the arguments as a command",
+			args:     []string{"echo", "hello world", "$HOME", "a|b"},
+			expected: "hello world,$HOME,a|b\n",
+		},
+		{
+			name:     "gives the command stdin",
+			args:     []string{"cat"},
+			stdin:    "input\n",
+			expected: "input\n",
+		},
+		{
+			name:   "fails with the command",
+			args:   []string{"fail"},
+			failed: true,
+		},
+		{
+			name:     "runs each line of stdin",
+			stdin:    "echo a b\nset name bob\necho $name | cat\n",
+			expected: "a,b\nbob\n",
+		},
+		{
+			name:     "stops at the first failed line",
+			stdin:    "echo a\nfail\necho b\n",
+			expected: "a\n",
+			failed:   true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(testRootCmd(), test.args, strings.NewReader(test.stdin), &stdout, &stderr, WithNoHistory())
+
+			if got := stdout.String(); got != test.expected {
+				t.Errorf("expected output %q but got %q", test.expected, got)
+			}
+
+			if test.failed {
+				if err == nil {
+					t.Errorf("expected an error but got nil")
+				}
+				if !strings.HasPrefix(stderr.String(), "Error: ") {
+					t.Errorf("expected the error to be written to stderr but got %q", stderr.String())
+				}
+			} else if err != nil {
+				t.Errorf("expected no error but got %v", err)
+			}
+		})
+	}
+}
+
+func TestInvalidDefaultAlias(t *testing.T) {
+	aliases := WithAliases(map[string]string{"greet": "echo hello", "bad=name": "echo"})
+
+	// Outside the interactive shell the error is written to stderr, and the valid aliases still work
+	var stdout, stderr bytes.Buffer
+	if err := run(testRootCmd(), []string{"greet"}, strings.NewReader(""), &stdout, &stderr, WithNoHistory(), aliases); err != nil {
+		t.Errorf("expected no error but got %v", err)
+	}
+	if got := stdout.String(); got != "hello\n" {
+		t.Errorf("expected the valid alias to be expanded but got %q", got)
+	}
+	if !strings.Contains(stderr.String(), `invalid alias name "bad=name"`) {
+		t.Errorf("expected the invalid alias to be reported but got %q", stderr.String())
+	}
+
+	// The interactive shell reports it in the history
+	m := New(testRootCmd(), WithNoHistory(), aliases).(Model)
+	if m.aliasErr == nil || !strings.Contains(m.aliasErr.Error(), `invalid alias name "bad=name"`) {
+		t.Errorf("expected the invalid alias to be reported when the shell starts but got %v", m.aliasErr)
+	}
+	if m.reportAliasError() == nil {
+		t.Errorf("expected the error to be added to the history")
+	}
+	if value, found := m.session.Alias("greet"); !found || value != "echo hello" {
+		t.Errorf("expected the valid alias to be set but got %q", value)
+	}
+}
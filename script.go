@@ -0,0 +1,81 @@
+package shell
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/cobrautils"
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/pkg/config/styles"
+	"github.com/DomBlack/bubble-shell/pkg/tui/errdisplay"
+	"github.com/spf13/cobra"
+)
+
+// RunScript runs each line read from the script against the root command without
+// starting the interactive shell, writing the output of the commands to os.Stdout and
+// os.Stderr. This allows the same commands to be used from files or pipes, such as
+// `my-app < setup.txt`.
+//
+// Lines are run in the same way as the interactive shell, with the same syntax, variables
+// and aliases (including the aliases the user has saved). Errors are written to os.Stderr
+// using plain styles, and running stops at the first line which fails, returning its error,
+// unless the [WithContinueOnError] option is given.
+//
+// The options are the same as for [New], however options which only affect the
+// interactive shell, such as key bindings, are ignored.
+func RunScript(rootCmd *cobra.Command, script io.Reader, options ...Option) error {
+	cfg := config.Default()
+	cfg.Styles = styles.Plain
+	for _, option := range options {
+		option(cfg)
+	}
+
+	return runScript(cfg, rootCmd, script, os.Stdout, os.Stderr)
+}
+
+// runScript runs the script using the config, writing the output of the commands
+// and any errors to stdout and stderr
+func runScript(cfg *config.Config, rootCmd *cobra.Command, script io.Reader, stdout io.Writer, stderr io.Writer) error {
+	executor := newExecutor(cfg, rootCmd)
+
+	s, aliasErr := newSession(cfg)
+	if aliasErr != nil {
+		reportScriptError(cfg, stderr, aliasErr)
+	}
+	if err := loadAliasFile(cfg, s); err != nil {
+		reportScriptError(cfg, stderr, err)
+	}
+
+	ctx := session.NewContext(cfg.RootContext, s)
+	return executor.RunScript(ctx, script, stdout, stderr, cobrautils.ScriptOptions{
+		ContinueOnError: cfg.ContinueOnError,
+		ReportError: func(err error) {
+			reportScriptError(cfg, stderr, err)
+		},
+	})
+}
+
+// reportScriptError writes the rendered error to w
+func reportScriptError(cfg *config.Config, w io.Writer, err error) {
+	_, _ = fmt.Fprintln(w, renderError(cfg, err))
+}
+
+// renderError renders the error, removing the padding added
+// to the end of each line when the error is rendered
+func renderError(cfg *config.Config, err error) string {
+	lines := strings.Split(errdisplay.New(cfg, err).View(), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// errorText renders the error without any styling, such as for copying it to the clipboard
+func errorText(cfg *config.Config, err error) string {
+	plain := *cfg
+	plain.Styles = styles.Plain
+	return renderError(&plain, err)
+}
@@ -0,0 +1,56 @@
+package shell
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/DomBlack/bubble-shell/internal/cobrautils"
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/internal/session"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	"github.com/cockroachdb/errors"
+	"github.com/spf13/cobra"
+)
+
+// newExecutor creates the executor for the commands of the shell, using the
+// command factory from the config if one has been provided
+func newExecutor(cfg *config.Config, rootCmd *cobra.Command) *cobrautils.Executor {
+	if cfg.CommandFactory != nil {
+		return cobrautils.NewFactoryExecutor(cfg.CommandFactory, cfg.CaptureStdout)
+	}
+	return cobrautils.NewExecutor(rootCmd, cfg.CaptureStdout)
+}
+
+// newSession creates a new session for the shell with the default aliases from the config,
+// returning an error for any of the aliases which are invalid, which are skipped
+func newSession(cfg *config.Config) (*session.Session, error) {
+	names := make([]string, 0, len(cfg.Aliases))
+	for name := range cfg.Aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	s := session.New()
+	var err error
+	for _, name := range names {
+		err = errors.CombineErrors(err, s.SetDefaultAlias(name, cfg.Aliases[name]))
+	}
+	return s, errors.Wrap(err, "invalid default aliases")
+}
+
+// loadAliasFile loads the aliases the user has defined into the session from
+// the alias file, which is stored next to the history file
+func loadAliasFile(cfg *config.Config, s *session.Session) error {
+	if cfg.HistoryFile == "" {
+		return nil
+	}
+
+	historyFile, err := history.FileLocation(cfg.HistoryFile)
+	if err != nil {
+		return errors.Wrap(err, "unable to get alias file location")
+	}
+
+	aliasFile := strings.TrimSuffix(historyFile, filepath.Ext(historyFile)) + ".aliases.json"
+	return s.LoadAliases(aliasFile)
+}
@@ -0,0 +1,137 @@
+package shell
+
+import (
+	"context"
+	"io"
+
+	"github.com/DomBlack/bubble-shell/internal/asciicast"
+	"github.com/DomBlack/bubble-shell/internal/clipboard"
+	"github.com/DomBlack/bubble-shell/internal/cobrautils"
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/cockroachdb/errors"
+	"github.com/rs/xid"
+)
+
+// shellAccess gives the builtin commands access to the shell, implementing [cobrautils.Shell]
+//
+// As commands run outside the bubbletea update loop, each request is passed to the shell
+// to be run within the update loop, where it can safely read and change the shell's state.
+type shellAccess struct {
+	cfg      *config.Config
+	requests chan func(m Model) (Model, tea.Cmd)
+}
+
+var _ cobrautils.Shell = (*shellAccess)(nil)
+
+func newShellAccess(cfg *config.Config) *shellAccess {
+	return &shellAccess{
+		cfg:      cfg,
+		requests: make(chan func(m Model) (Model, tea.Cmd)),
+	}
+}
+
+// run runs fn within the update loop of the shell, waiting for it to finish
+func (s *shellAccess) run(ctx context.Context, fn func(m Model) (Model, tea.Cmd)) error {
+	done := make(chan struct{})
+	request := func(m Model) (Model, tea.Cmd) {
+		defer close(done)
+		return fn(m)
+	}
+
+	select {
+	case s.requests <- request:
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+
+	<-done
+	return nil
+}
+
+func (s *shellAccess) HistoryItems(ctx context.Context) ([]history.Item, error) {
+	var items []history.Item
+	err := s.run(ctx, func(m Model) (Model, tea.Cmd) {
+		items = append(items, m.history.Items...)
+		return m, nil
+	})
+	return items, err
+}
+
+func (s *shellAccess) ClearHistory(ctx context.Context) error {
+	return s.run(ctx, func(m Model) (Model, tea.Cmd) {
+		return m, m.history.Clear()
+	})
+}
+
+func (s *shellAccess) RemoveHistoryItem(ctx context.Context, id xid.ID) error {
+	var removeErr error
+	err := s.run(ctx, func(m Model) (Model, tea.Cmd) {
+		if m.history.IsRunning(id) {
+			removeErr = errors.New("unable to delete a command while it is running")
+			return m, nil
+		}
+		return m, m.history.RemoveItem(id)
+	})
+	if err != nil {
+		return err
+	}
+	return removeErr
+}
+
+func (s *shellAccess) ErrorText(err error) string {
+	return errorText(s.cfg, err)
+}
+
+func (s *shellAccess) CopyToClipboard(ctx context.Context, text string) error {
+	var copyErr error
+	err := s.run(ctx, func(m Model) (Model, tea.Cmd) {
+		copyErr = clipboard.Copy(m.cfg.Output, text)
+		return m, nil
+	})
+	if err != nil {
+		return err
+	}
+	return copyErr
+}
+
+func (s *shellAccess) ExportTranscript(ctx context.Context, w io.Writer, format history.ExportFormat) error {
+	items, err := s.HistoryItems(ctx)
+	if err != nil {
+		return err
+	}
+	return history.ExportItems(w, s.cfg, items, format)
+}
+
+func (s *shellAccess) Replay(ctx context.Context, title string, recording *asciicast.Recording, speed float64) error {
+	mode := &ReplayMode{
+		Title:     title,
+		Speed:     speed,
+		recording: recording,
+		done:      make(chan struct{}),
+	}
+
+	err := s.run(ctx, func(m Model) (Model, tea.Cmd) {
+		return m, m.Enter(mode)
+	})
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-mode.done:
+		return nil
+	case <-ctx.Done():
+		return errors.WithStack(ctx.Err())
+	}
+}
+
+// waitForShellRequest is a [tea.Cmd] which waits for the
+// next request from a builtin command
+func (m Model) waitForShellRequest() tea.Msg {
+	return shellRequestMsg{
+		id: m.id,
+		fn: <-m.shellAccess.requests,
+	}
+}
@@ -0,0 +1,613 @@
+package shell
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Indicators shown before the prompt for the vi mode the input is in, matching
+// those bash shows with `show-mode-in-prompt` enabled
+const (
+	viInsertIndicator = "(ins)"
+	viNormalIndicator = "(cmd)"
+	viVisualIndicator = "(vis)"
+)
+
+// viState is the state of vi editing which is kept as the input moves between
+// the vi modes, such as the last change made so it can be repeated by `.`
+//
+// It is shared between the copies of the model, as they are passed by value.
+type viState struct {
+	lastChange *viCommand // The last change made to the input, which is repeated by `.`
+	lastFind   viCommand  // The last f, F, t or T motion, which is repeated by `;` and `,`
+
+	insert      *viCommand // The command which entered insert mode, which the text typed is added to when it is left
+	insertStart int        // The position in the input insert mode was entered at
+}
+
+// viCommand is a command typed in the vi normal or visual modes, such as `3dw` or `fx`
+type viCommand struct {
+	opCount  int    // The count typed before the operator, or 0 if there wasn't one
+	count    int    // The count typed before the command or motion, or 0 if there wasn't one
+	operator rune   // The operator applied over the motion; d, c or y, or 0 if there is none
+	key      rune   // The command or motion
+	char     rune   // The character given to f, F, t, T and r
+	text     string // The text typed in insert mode after the command, so the change can be repeated
+}
+
+// times returns the number of times the command should be applied
+func (c viCommand) times() int {
+	n := 1
+	if c.opCount > 0 {
+		n *= c.opCount
+	}
+	if c.count > 0 {
+		n *= c.count
+	}
+	return n
+}
+
+// awaitingChar reports whether the command is waiting for the character given to f, F, t, T or r
+func (c viCommand) awaitingChar() bool {
+	return c.key != 0 && c.char == 0
+}
+
+// add adds the typed rune to the command, returning true once the command is complete.
+// Operators are only parsed if operators is true, as in visual mode they apply to the selection.
+func (c *viCommand) add(r rune, operators bool) (complete bool) {
+	switch {
+	case c.awaitingChar():
+		c.char = r
+		return true
+
+	case r >= '1' && r <= '9' || r == '0' && c.count > 0:
+		c.count = c.count*10 + int(r-'0')
+		return false
+
+	case operators && c.operator == 0 && strings.ContainsRune("dcy", r):
+		c.operator = r
+		c.opCount, c.count = c.count, 0
+		return false
+	}
+
+	c.key = r
+	if r == 'r' && c.operator == 0 || strings.ContainsRune("fFtT", r) {
+		return false
+	}
+
+	// Commands which are shorthand for an operator and a motion
+	if c.operator == 0 {
+		switch r {
+		case 'x':
+			c.operator, c.key = 'd', 'l'
+		case 'X':
+			c.operator, c.key = 'd', 'h'
+		case 'D':
+			c.operator, c.key = 'd', '$'
+		case 'C':
+			c.operator, c.key = 'c', '$'
+		case 's':
+			c.operator, c.key = 'c', 'l'
+		case 'S':
+			c.operator, c.key = 'c', 'c'
+		}
+	}
+	return true
+}
+
+// viKey returns the command a key press is for in the vi normal and visual modes, with the
+// arrow and editing keys treated as the vi command they match, or 0 if it isn't one.
+func viKey(msg tea.KeyMsg) rune {
+	switch msg.Type {
+	case tea.KeyRunes:
+		if len(msg.Runes) == 1 && !msg.Alt {
+			return msg.Runes[0]
+		}
+	case tea.KeySpace:
+		return ' '
+	case tea.KeyLeft, tea.KeyBackspace:
+		return 'h'
+	case tea.KeyRight:
+		return 'l'
+	case tea.KeyHome:
+		return '0'
+	case tea.KeyEnd:
+		return '$'
+	case tea.KeyDelete:
+		return 'x'
+	}
+	return 0
+}
+
+// runViCommand runs a complete command typed in the vi normal mode. If repeat is true the
+// command is being repeated by `.`, so any text it typed in insert mode is inserted again.
+func (m Model) runViCommand(cmd viCommand, repeat bool) (Model, tea.Cmd) {
+	value := []rune(m.input.Value())
+	pos := m.input.Position()
+	e := m.lineEditor
+
+	if cmd.operator != 0 {
+		var start, end int
+		switch {
+		case cmd.key == cmd.operator:
+			// dd, cc and yy apply to the whole line
+			start, end = 0, len(value)
+
+		case cmd.operator == 'c' && (cmd.key == 'w' || cmd.key == 'W') && pos < len(value) && !unicode.IsSpace(value[pos]):
+			// Like vi, cw changes to the end of the word rather than up to the next one
+			start, end = pos, viChangeWordEnd(value, pos, cmd.times(), cmd.key == 'W')
+
+		default:
+			target, inclusive, ok := m.viMotion(value, pos, cmd)
+			if !ok {
+				return m, nil
+			}
+			start, end = viRange(pos, target, inclusive, len(value))
+		}
+
+		return m.viOperate(cmd, value, start, end, repeat)
+	}
+
+	n := cmd.times()
+	switch cmd.key {
+	case 'i', 'a', 'I', 'A':
+		at := pos
+		switch cmd.key {
+		case 'a':
+			if len(value) > 0 {
+				at++
+			}
+		case 'I':
+			at = viFirstNonBlank(value)
+		case 'A':
+			at = len(value)
+		}
+		return m.viInsert(cmd, value, at, at, repeat)
+
+	case 'p', 'P':
+		if len(e.killRing) == 0 {
+			return m, nil
+		}
+
+		text := []rune(strings.Repeat(e.killRing[len(e.killRing)-1], n))
+		at := pos
+		if cmd.key == 'p' && len(value) > 0 {
+			at++
+		}
+		e.change(&m.input, inputState{
+			value: string(value[:at]) + string(text) + string(value[at:]),
+			pos:   at + len(text) - 1,
+		}, editOther)
+
+	case 'r':
+		if pos+n > len(value) {
+			return m, nil
+		}
+
+		for i := pos; i < pos+n; i++ {
+			value[i] = cmd.char
+		}
+		e.change(&m.input, inputState{value: string(value), pos: pos + n - 1}, editOther)
+
+	case '~':
+		if len(value) == 0 {
+			return m, nil
+		}
+
+		end := pos + n
+		if end > len(value) {
+			end = len(value)
+		}
+		for i := pos; i < end; i++ {
+			if unicode.IsUpper(value[i]) {
+				value[i] = unicode.ToLower(value[i])
+			} else {
+				value[i] = unicode.ToUpper(value[i])
+			}
+		}
+		e.change(&m.input, inputState{value: string(value), pos: end}, editOther)
+
+	case 'u':
+		for i := 0; i < n; i++ {
+			if !e.undoChange(&m.input) {
+				break
+			}
+		}
+		m.clampViCursor()
+		return m, nil
+
+	case '.':
+		if m.vi.lastChange == nil {
+			return m, nil
+		}
+
+		change := *m.vi.lastChange
+		if cmd.count > 0 {
+			change.opCount, change.count = 0, cmd.count
+		}
+		return m.runViCommand(change, true)
+
+	case 'v':
+		return m, m.Enter(&ViVisualMode{anchor: pos})
+
+	default:
+		if target, _, ok := m.viMotion(value, pos, cmd); ok {
+			m.input.SetCursor(target)
+			m.clampViCursor()
+		}
+		return m, nil
+	}
+
+	m.vi.lastChange = &cmd
+	m.clampViCursor()
+	return m, nil
+}
+
+// viOperate applies the operator of the command over the runes of the input from start to end
+func (m Model) viOperate(cmd viCommand, value []rune, start, end int, repeat bool) (Model, tea.Cmd) {
+	e := m.lineEditor
+	if start < end {
+		e.cut(string(value[start:end]))
+	}
+
+	switch cmd.operator {
+	case 'y':
+		m.input.SetCursor(start)
+		m.clampViCursor()
+
+	case 'd':
+		if start == end {
+			return m, nil
+		}
+
+		e.change(&m.input, inputState{value: string(value[:start]) + string(value[end:]), pos: start}, editOther)
+		m.vi.lastChange = &cmd
+		m.clampViCursor()
+
+	case 'c':
+		return m.viInsert(cmd, value, start, end, repeat)
+	}
+
+	return m, nil
+}
+
+// viInsert replaces the runes of the input from start to end, which is empty for commands which only
+// insert, with the text typed in insert mode. If the command is being repeated the text it typed last
+// time is inserted, otherwise insert mode is entered for the user to type it.
+func (m Model) viInsert(cmd viCommand, value []rune, start, end int, repeat bool) (Model, tea.Cmd) {
+	e := m.lineEditor
+	after := string(value[:start]) + string(value[end:])
+
+	if repeat {
+		text := []rune(cmd.text)
+		if len(text) > 0 || start < end {
+			e.change(&m.input, inputState{
+				value: string(value[:start]) + string(text) + string(value[end:]),
+				pos:   start + len(text) - 1,
+			}, editOther)
+		}
+
+		m.vi.lastChange = &cmd
+		m.clampViCursor()
+		return m, nil
+	}
+
+	if start < end {
+		// The text typed is undone along with the text it replaced
+		e.change(&m.input, inputState{value: after, pos: start}, editInsert)
+	} else {
+		m.input.SetCursor(start)
+		e.lastEdit = editMove
+	}
+
+	m.vi.insert = &cmd
+	m.vi.insertStart = start
+	return m, m.Enter(&CommandEntryMode{KeepInputContent: true, KeepCursorPosition: true})
+}
+
+// leaveViInsert leaves insert mode for the vi normal mode, recording the text typed so the
+// change which entered insert mode can be repeated
+func (m Model) leaveViInsert() (Model, tea.Cmd) {
+	value := []rune(m.input.Value())
+	pos := m.input.Position()
+
+	if insert := m.vi.insert; insert != nil {
+		if m.vi.insertStart <= pos && pos <= len(value) {
+			insert.text = string(value[m.vi.insertStart:pos])
+		}
+		m.vi.lastChange = insert
+		m.vi.insert = nil
+	}
+
+	// Like vi, the cursor moves back onto the last character typed
+	if pos > 0 {
+		m.input.SetCursor(pos - 1)
+	}
+	return m, m.Enter(&ViNormalMode{KeepInputContent: true})
+}
+
+// clampViCursor keeps the cursor on a character of the input, as in the vi normal
+// and visual modes it can't be after the last character
+func (m *Model) clampViCursor() {
+	if length := len([]rune(m.input.Value())); m.input.Position() >= length && length > 0 {
+		m.input.SetCursor(length - 1)
+	}
+}
+
+// viIndicator returns the indicator for the vi mode the input is in to show before the prompt,
+// or an empty string if vi mode isn't enabled or a command is running
+func (m Model) viIndicator() string {
+	if !m.cfg.ViMode {
+		return ""
+	}
+
+	indicator := ""
+	switch mode := m.mode.(type) {
+	case *CommandEntryMode, *AutoCompleteMode:
+		indicator = viInsertIndicator
+	case *ViNormalMode:
+		indicator = viNormalIndicator
+	case *ViVisualMode:
+		indicator = viVisualIndicator
+	case *HistoryLookbackMode:
+		indicator = viInsertIndicator
+		if mode.ViNormal {
+			indicator = viNormalIndicator
+		}
+	case *HistorySearchMode:
+		indicator = viInsertIndicator
+		if mode.ViNormal {
+			indicator = viNormalIndicator
+		}
+	default:
+		return ""
+	}
+
+	return m.cfg.Styles.ViModeIndicator.Render(indicator) + " "
+}
+
+// viMotion returns the position the motion of the command moves the cursor to, and whether
+// the character at that position is included when an operator is applied over the motion.
+func (m Model) viMotion(value []rune, pos int, cmd viCommand) (target int, inclusive bool, ok bool) {
+	n := cmd.times()
+
+	switch cmd.key {
+	case 'h':
+		target = pos - n
+		if target < 0 {
+			target = 0
+		}
+		return target, false, true
+
+	case 'l', ' ':
+		target = pos + n
+		if target > len(value) {
+			target = len(value)
+		}
+		return target, false, true
+
+	case '0':
+		return 0, false, true
+
+	case '^':
+		return viFirstNonBlank(value), false, true
+
+	case '$':
+		if len(value) == 0 {
+			return 0, false, true
+		}
+		return len(value) - 1, true, true
+
+	case 'w', 'W':
+		target = pos
+		for i := 0; i < n; i++ {
+			target = viNextWordStart(value, target, cmd.key == 'W')
+		}
+		return target, false, true
+
+	case 'b', 'B':
+		target = pos
+		for i := 0; i < n; i++ {
+			target = viPreviousWordStart(value, target, cmd.key == 'B')
+		}
+		return target, false, true
+
+	case 'e', 'E':
+		target = pos
+		for i := 0; i < n; i++ {
+			target = viWordEnd(value, target, cmd.key == 'E')
+		}
+		return target, true, true
+
+	case 'f', 'F', 't', 'T':
+		m.vi.lastFind = viCommand{key: cmd.key, char: cmd.char}
+		return viFind(value, pos, cmd.key, cmd.char, n)
+
+	case ';', ',':
+		find := m.vi.lastFind
+		if find.key == 0 {
+			return pos, false, false
+		}
+
+		if cmd.key == ',' {
+			// Repeat the find in the opposite direction
+			if unicode.IsUpper(find.key) {
+				find.key = unicode.ToLower(find.key)
+			} else {
+				find.key = unicode.ToUpper(find.key)
+			}
+		}
+		return viFind(value, pos, find.key, find.char, n)
+	}
+
+	return pos, false, false
+}
+
+// viRange returns the range of the input covered by moving from pos to target,
+// for an operator to be applied over
+func viRange(pos, target int, inclusive bool, length int) (start, end int) {
+	if target < pos {
+		return target, pos
+	}
+
+	end = target
+	if inclusive {
+		end++
+	}
+	if end > length {
+		end = length
+	}
+	return pos, end
+}
+
+// viClass returns the class of a rune for vi word motions; a word is a run of letters, digits and
+// underscores or a run of other non-blank characters. For WORD motions all non-blank characters
+// are a single class. Whitespace, which separates words, is class 0.
+func viClass(r rune, bigWord bool) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case bigWord || isWordRune(r) || r == '_':
+		return 1
+	default:
+		return 2
+	}
+}
+
+// viNextWordStart returns the position of the start of the word after pos
+func viNextWordStart(value []rune, pos int, bigWord bool) int {
+	if pos >= len(value) {
+		return len(value)
+	}
+
+	class := viClass(value[pos], bigWord)
+	for pos < len(value) && class != 0 && viClass(value[pos], bigWord) == class {
+		pos++
+	}
+	for pos < len(value) && viClass(value[pos], bigWord) == 0 {
+		pos++
+	}
+	return pos
+}
+
+// viPreviousWordStart returns the position of the start of the word before pos
+func viPreviousWordStart(value []rune, pos int, bigWord bool) int {
+	pos--
+	for pos > 0 && viClass(value[pos], bigWord) == 0 {
+		pos--
+	}
+	if pos <= 0 {
+		return 0
+	}
+
+	class := viClass(value[pos], bigWord)
+	for pos > 0 && viClass(value[pos-1], bigWord) == class {
+		pos--
+	}
+	return pos
+}
+
+// viWordEnd returns the position of the last character of the word after pos
+func viWordEnd(value []rune, pos int, bigWord bool) int {
+	pos++
+	for pos < len(value) && viClass(value[pos], bigWord) == 0 {
+		pos++
+	}
+	if pos >= len(value) {
+		if len(value) == 0 {
+			return 0
+		}
+		return len(value) - 1
+	}
+
+	class := viClass(value[pos], bigWord)
+	for pos+1 < len(value) && viClass(value[pos+1], bigWord) == class {
+		pos++
+	}
+	return pos
+}
+
+// viChangeWordEnd returns the end of the range changed by `cw` from pos, which
+// unlike `dw` doesn't include the whitespace after the last word
+func viChangeWordEnd(value []rune, pos, n int, bigWord bool) int {
+	end := pos
+	for i := 0; i < n && end < len(value); i++ {
+		for i > 0 && end < len(value) && viClass(value[end], bigWord) == 0 {
+			end++
+		}
+		if end >= len(value) {
+			break
+		}
+
+		class := viClass(value[end], bigWord)
+		for end < len(value) && viClass(value[end], bigWord) == class {
+			end++
+		}
+	}
+	return end
+}
+
+// viFirstNonBlank returns the position of the first non-blank character of the input
+func viFirstNonBlank(value []rune) int {
+	for i, r := range value {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return len(value)
+}
+
+// viFind returns the position of the nth occurrence of char after pos for f and t, or before pos for
+// F and T. Like vi, t and T stop just before the character and F and T are exclusive motions.
+func viFind(value []rune, pos int, findKey rune, char rune, n int) (target int, inclusive bool, ok bool) {
+	switch findKey {
+	case 'f', 't':
+		for i := pos + 1; i < len(value); i++ {
+			if value[i] == char {
+				if n--; n == 0 {
+					if findKey == 't' {
+						return i - 1, true, true
+					}
+					return i, true, true
+				}
+			}
+		}
+
+	case 'F', 'T':
+		for i := pos - 1; i >= 0; i-- {
+			if value[i] == char {
+				if n--; n == 0 {
+					if findKey == 'T' {
+						return i + 1, false, true
+					}
+					return i, false, true
+				}
+			}
+		}
+	}
+
+	return pos, false, false
+}
+
+// viEntryMode returns the mode to return to once the user has finished with a mode entered
+// from entering a command, which is the vi normal mode if it was entered from there
+func viEntryMode(viNormal bool, keepInputContent bool) Mode {
+	if viNormal {
+		return &ViNormalMode{KeepInputContent: keepInputContent}
+	}
+	return &CommandEntryMode{KeepInputContent: keepInputContent}
+}
+
+// Help for the vi commands, which are not part of the [KeyMap] as they are the same in every vi
+var (
+	viInsertHelp = key.NewBinding(key.WithKeys("i"), key.WithHelp("i/a", "insert"))
+	viDeleteHelp = key.NewBinding(key.WithKeys("d"), key.WithHelp("d/c/y", "delete/change/yank"))
+	viUndoHelp   = key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "undo"))
+	viRepeatHelp = key.NewBinding(key.WithKeys("."), key.WithHelp(".", "repeat change"))
+	viVisualHelp = key.NewBinding(key.WithKeys("v"), key.WithHelp("v", "visual mode"))
+	viMoveHelp   = key.NewBinding(key.WithKeys("w"), key.WithHelp("w/b/e/0/$", "move"))
+	viFindHelp   = key.NewBinding(key.WithKeys("f"), key.WithHelp("f/t", "find character"))
+)
@@ -0,0 +1,155 @@
+package shell
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/DomBlack/bubble-shell/internal/config"
+	"github.com/DomBlack/bubble-shell/pkg/tui/history"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+)
+
+// newViModel returns a model with vi mode enabled in insert mode
+func newViModel() Model {
+	cfg := config.Default()
+	cfg.ViMode = true
+
+	m := newModel(cfg, &cobra.Command{})
+	m.mode = &CommandEntryMode{}
+	m, _ = m.mode.Enter(m)
+	return m
+}
+
+// viKeys returns a key press for each rune of keys, with escape as `\x1b`
+func viKeys(keys string) []tea.KeyMsg {
+	msgs := make([]tea.KeyMsg, 0, len(keys))
+	for _, r := range keys {
+		if r == '\x1b' {
+			msgs = append(msgs, tea.KeyMsg{Type: tea.KeyEscape})
+		} else {
+			msgs = append(msgs, typed(string(r)))
+		}
+	}
+	return msgs
+}
+
+// pressKeys passes the key presses to the inputs and the mode, as the shell does,
+// entering any modes the shell is told to enter
+func pressKeys(m Model, keys ...tea.KeyMsg) Model {
+	queue := make([]tea.Msg, 0, len(keys))
+	for _, msg := range keys {
+		queue = append(queue, msg)
+	}
+
+	for len(queue) > 0 {
+		msg := queue[0]
+		queue = queue[1:]
+
+		var cmd tea.Cmd
+		m, _ = m.updateInput(msg)
+		m.searchInput, _ = m.searchInput.Update(msg)
+		m, cmd = m.mode.Update(m, msg)
+		if cmd == nil {
+			continue
+		}
+
+		if enter, ok := cmd().(enterModeMsg); ok {
+			m, _ = m.mode.Leave(m)
+			m.mode = enter.Mode
+			m, cmd = m.mode.Enter(m)
+
+			// Modes such as looking back through the history are entered with the key which triggered them
+			if cmd != nil {
+				if msg, ok := cmd().(tea.KeyMsg); ok {
+					queue = append([]tea.Msg{msg}, queue...)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func TestViEditing(t *testing.T) {
+	tests := []struct {
+		keys     string
+		expected string
+		pos      int
+	}{
+		{keys: "deploy --env prod\x1b0dw", expected: "--env prod", pos: 0},
+		{keys: "deploy --env prod\x1b0dW", expected: "--env prod", pos: 0},
+		{keys: "deploy api\x1bbcwweb\x1b", expected: "deploy web", pos: 9},
+		{keys: "deploy api\x1b0de", expected: " api", pos: 0},
+		{keys: "deploy api\x1b0ed$", expected: "deplo", pos: 4},
+		{keys: "deploy\x1b0$x", expected: "deplo", pos: 4},
+		{keys: "deploy --env prod\x1b0dtp", expected: "ploy --env prod", pos: 0},
+		{keys: "deploy --env prod\x1b0f-D", expected: "deploy ", pos: 6},
+		{keys: "deploy --env prod\x1bFeC", expected: "deploy --", pos: 9},
+		{keys: "a-b-c\x1b0f-;x", expected: "a-bc", pos: 3},
+		{keys: "a b c d\x1b02dw", expected: "c d", pos: 0},
+		{keys: "a b c d\x1b0d2w", expected: "c d", pos: 0},
+		{keys: "a b c d\x1b0dw.", expected: "c d", pos: 0},
+		{keys: "a b c d\x1b0dw2.", expected: "d", pos: 0},
+		{keys: "one two\x1b0cwuno\x1bw.", expected: "uno uno", pos: 6},
+		{keys: "api\x1bIdeploy \x1b", expected: "deploy api", pos: 6},
+		{keys: "ab\x1b0xp", expected: "ba", pos: 1},
+		{keys: "deplay\x1b0faro", expected: "deploy", pos: 4},
+		{keys: "abc\x1b0~~", expected: "ABc", pos: 2},
+		{keys: "abc\x1b0d\x1bx", expected: "bc", pos: 0},
+		{keys: "deploy api\x1b0dwu", expected: "deploy api", pos: 0},
+		{keys: "deploy api\x1bbcwweb\x1bu", expected: "deploy api", pos: 7},
+		{keys: "deploy\x1bA api\x1bu", expected: "deploy", pos: 5},
+		{keys: "deploy --env prod\x1b0vwd", expected: "-env prod", pos: 0},
+		{keys: "deploy api\x1b0vey$p", expected: "deploy apideploy", pos: 15},
+		{keys: "deploy api\x1b0veolcrun\x1b", expected: "drun api", pos: 3},
+	}
+
+	for _, test := range tests {
+		m := pressKeys(newViModel(), viKeys(test.keys)...)
+
+		if got := m.input.Value(); got != test.expected || m.input.Position() != test.pos {
+			t.Errorf("%q: expected %q with the cursor at %d but got %q with the cursor at %d", test.keys, test.expected, test.pos, got, m.input.Position())
+		}
+	}
+}
+
+func TestViModeWithHistory(t *testing.T) {
+	m := newViModel()
+	m.history.Items = append(m.history.Items,
+		history.NewItem("> ", "echo one", history.SuccessStatus),
+		history.NewItem("> ", "echo two", history.SuccessStatus),
+	)
+
+	m = pressKeys(m, viKeys("\x1bkk")...)
+	if got := m.input.Value(); got != "echo one" {
+		t.Errorf("expected k to look back through the history to %q but got %q", "echo one", got)
+	}
+	if indicator := m.viIndicator(); !strings.Contains(indicator, viNormalIndicator) {
+		t.Errorf("expected the normal mode indicator while looking back from normal mode but got %q", indicator)
+	}
+
+	m = pressKeys(m, viKeys("j\x1b0dw")...)
+	if _, ok := m.mode.(*ViNormalMode); !ok {
+		t.Errorf("expected to return to the normal mode but the mode is %T", m.mode)
+	}
+	if got := m.input.Value(); got != "two" {
+		t.Errorf("expected to edit the line from the history but got %q", got)
+	}
+
+	m = pressKeys(m, viKeys("/")...)
+	m = pressKeys(m, typed("one"), tea.KeyMsg{Type: tea.KeyEscape})
+	if _, ok := m.mode.(*ViNormalMode); !ok {
+		t.Errorf("expected escape to leave the search for the normal mode but the mode is %T", m.mode)
+	}
+	if got := m.input.Value(); got != "echo one" {
+		t.Errorf("expected the search result to be kept but got %q", got)
+	}
+
+	m = pressKeys(m, viKeys("A two")...)
+	if indicator := m.viIndicator(); !strings.Contains(indicator, viInsertIndicator) {
+		t.Errorf("expected the insert mode indicator but got %q", indicator)
+	}
+	if got := m.input.Value(); got != "echo one two" {
+		t.Errorf("expected to append to the line but got %q", got)
+	}
+}